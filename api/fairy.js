@@ -1,9 +1,9 @@
 const express = require('express');
 const router = express.Router();
-const { authMiddleware } = require('../auth');
+const { authMiddleware, requireVerifiedEmail } = require('../auth');
 const db = require('../db');
 
-router.post('/toggle', authMiddleware, async (req, res) => {
+router.post('/toggle', authMiddleware, requireVerifiedEmail, async (req, res) => {
   try {
     const { is_active, max_transaction_amount } = req.body;
     const userId = req.user.id;
@@ -29,7 +29,7 @@ router.get('/status', authMiddleware, async (req, res) => {
   }
 });
 
-router.post('/request', authMiddleware, async (req, res) => {
+router.post('/request', authMiddleware, requireVerifiedEmail, async (req, res) => {
   try {
     const { location, amount, description } = req.body;
     const userId = req.user.id;
@@ -104,7 +104,7 @@ router.get('/requests/accepted', authMiddleware, async (req, res) => {
   }
 });
 
-router.post('/request/accept', authMiddleware, async (req, res) => {
+router.post('/request/accept', authMiddleware, requireVerifiedEmail, async (req, res) => {
   try {
     const { request_id } = req.body;
     const fairyId = req.user.id;
@@ -166,7 +166,7 @@ router.post('/request/cancel', authMiddleware, async (req, res) => {
   }
 });
 
-router.post('/request/confirm', authMiddleware, async (req, res) => {
+router.post('/request/confirm', authMiddleware, requireVerifiedEmail, async (req, res) => {
   try {
     const { request_id } = req.body;
     const fairyId = req.user.id;
@@ -197,7 +197,7 @@ router.post('/request/confirm', authMiddleware, async (req, res) => {
   }
 });
 
-router.post('/request/requestor-confirm', authMiddleware, async (req, res) => {
+router.post('/request/requestor-confirm', authMiddleware, requireVerifiedEmail, async (req, res) => {
   try {
     const { request_id } = req.body;
     const userId = req.user.id;
@@ -228,7 +228,7 @@ router.post('/request/requestor-confirm', authMiddleware, async (req, res) => {
   }
 });
 
-router.post('/request/rate', authMiddleware, async (req, res) => {
+router.post('/request/rate', authMiddleware, requireVerifiedEmail, async (req, res) => {
   try {
     const { request_id, rating, comment } = req.body;
     const userId = req.user.id;
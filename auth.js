@@ -68,6 +68,20 @@ async function authMiddleware(req, res, next) {
   }
 }
 
+async function requireVerifiedEmail(req, res, next) {
+  try {
+    const verified = await db.isEmailVerified(req.user.id);
+    if (!verified) {
+      return res.status(403).json({ error: 'Email address must be verified' });
+    }
+
+    next();
+  } catch (err) {
+    console.error('Email verification check error:', err);
+    res.status(500).json({ error: 'Internal server error' });
+  }
+}
+
 async function login(studentID, password) {
   try {
     const user = await db.getUserByStudentId(studentID);
@@ -136,6 +150,7 @@ module.exports = {
   verifyToken,
   extractUserID,
   authMiddleware,
+  requireVerifiedEmail,
   login,
   register
 }; 
\ No newline at end of file
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func runAuditVerify(args []string) {
+	fs := flag.NewFlagSet("audit-verify", flag.ExitOnError)
+	fs.Parse(args)
+
+	db, err := models.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	count, head, err := db.VerifyAuditChain()
+	var chainErr *models.AuditChainError
+	if errors.As(err, &chainErr) {
+		fmt.Printf("Audit log verification FAILED after %d intact events\n", count)
+		fmt.Printf("Event %d: %s\n", chainErr.EventID, chainErr.Reason)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("Audit verification failed: %v", err)
+	}
+
+	fmt.Printf("Audit log intact: %d events verified\n", count)
+	if head != "" {
+		fmt.Printf("Head hash: %s\n", head)
+	}
+}
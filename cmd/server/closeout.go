@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pyne/flexibudget/pkg/closeout"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func runCloseout(args []string) {
+	fs := flag.NewFlagSet("closeout", flag.ExitOnError)
+	termName := fs.String("term", "", `name of the term to close, e.g. "Fall 2026"`)
+	dryRun := fs.Bool("dry-run", false, "print the close-out report without applying it")
+	force := fs.Bool("force", false, "close the term even if it has not ended yet")
+	fs.Parse(args)
+
+	if *termName == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, err := models.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	report, err := closeout.Run(db, *termName, closeout.Options{DryRun: *dryRun, Force: *force})
+	if err != nil {
+		log.Fatalf("Close-out failed: %v", err)
+	}
+
+	fmt.Printf("Close-out for %s (%s to %s)\n\n", report.Term.Name,
+		report.Term.StartsAt.Format("2006-01-02"), report.Term.EndsAt.Format("2006-01-02"))
+	fmt.Printf("%-12s %-12s %-9s %10s %7s %10s %10s %10s %10s\n",
+		"Student", "Plan", "Rule", "Balance", "Swipes", "Converted", "Forfeited", "Rollover", "After")
+	for _, a := range report.Accounts {
+		fmt.Printf("%-12s %-12s %-9s %10.2f %7d %10.2f %10.2f %10.2f %10.2f\n",
+			a.StudentID, a.Plan, a.Rule, a.BalanceBefore, a.SwipesRemaining, a.Converted, a.Forfeited, a.RolledOver, a.BalanceAfter)
+	}
+
+	fmt.Printf("\n%d accounts: $%.2f converted, $%.2f forfeited, $%.2f rolled over, %d swipes cleared\n",
+		len(report.Accounts), report.TotalConverted, report.TotalForfeited, report.TotalRolledOver, report.SwipesForfeited)
+	if report.DryRun {
+		fmt.Println("Dry run: no changes applied.")
+	} else {
+		fmt.Printf("%s is now closed to new transactions.\n", report.Term.Name)
+	}
+}
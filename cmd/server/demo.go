@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/demo"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func runDemoSeed(args []string) {
+	fs := flag.NewFlagSet("demo-seed", flag.ExitOnError)
+	fs.Parse(args)
+
+	db, err := models.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	result, err := demo.Seed(db, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to seed demo data: %v", err)
+	}
+
+	fmt.Printf("Created %d demo accounts with %d transactions\n\n", result.Created, result.Transactions)
+	fmt.Println("Demo accounts (student_id: password)")
+	for _, account := range result.Accounts {
+		fmt.Printf("%s (%s, %s): %s\n", account.Name, account.StudentID, account.Role, account.Password)
+	}
+}
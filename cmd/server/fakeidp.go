@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/pyne/flexibudget/pkg/fakeidp"
+)
+
+type userFlags []fakeidp.User
+
+func (u *userFlags) String() string {
+	return fmt.Sprint(len(*u), " users")
+}
+
+func (u *userFlags) Set(value string) error {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return fmt.Errorf(`expected "student_id:Name:email", got %q`, value)
+	}
+	*u = append(*u, fakeidp.User{
+		Subject:       "campus-" + parts[0],
+		StudentID:     parts[0],
+		Name:          parts[1],
+		Email:         parts[2],
+		EmailVerified: true,
+	})
+	return nil
+}
+
+func runFakeIdP(args []string) {
+	var users userFlags
+	fs := flag.NewFlagSet("fake-idp", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:9000", "address to listen on")
+	clientID := fs.String("client-id", "flexibudget", "OIDC client ID to accept")
+	clientSecret := fs.String("client-secret", "", "OIDC client secret to require, if any")
+	fs.Var(&users, "user", `account to offer as "student_id:Name:email" (repeatable)`)
+	fs.Parse(args)
+
+	if len(users) == 0 {
+		users.Set("20260001:Demo Student:demo.student@dons.usfca.edu")
+	}
+
+	idp, err := fakeidp.New("http://"+*addr, *clientID)
+	if err != nil {
+		log.Fatalf("Failed to start fake identity provider: %v", err)
+	}
+	idp.ClientSecret = *clientSecret
+	for _, u := range users {
+		idp.AddUser(u)
+	}
+
+	fmt.Printf("Fake identity provider running at %s (client %s, %d accounts)\n", idp.Issuer, *clientID, len(users))
+	fmt.Printf("Start the server with OIDC_ISSUER=%s OIDC_CLIENT_ID=%s OIDC_STUDENT_ID_CLAIM=student_id\n", idp.Issuer, *clientID)
+	log.Fatal(http.ListenAndServe(*addr, idp))
+}
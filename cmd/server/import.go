@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pyne/flexibudget/pkg/importer"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func runImport(args []string) {
+	mapping := importer.DefaultMapping()
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	studentID := fs.String("student-id", "", "student ID to import transactions for")
+	file := fs.String("file", "", "path to the campus card CSV statement")
+	dryRun := fs.Bool("dry-run", false, "preview the import without applying it")
+	delimiter := fs.String("delimiter", ",", "CSV field delimiter")
+	fs.StringVar(&mapping.DateColumn, "date-column", mapping.DateColumn, "CSV column holding the transaction date")
+	fs.StringVar(&mapping.AmountColumn, "amount-column", mapping.AmountColumn, "CSV column holding the amount")
+	fs.StringVar(&mapping.LocationColumn, "location-column", mapping.LocationColumn, "CSV column holding the location")
+	fs.StringVar(&mapping.DescriptionColumn, "description-column", mapping.DescriptionColumn, "CSV column holding the description")
+	fs.StringVar(&mapping.DateFormat, "date-format", "", "Go time layout for the date column")
+	fs.BoolVar(&mapping.DebitsNegative, "debits-negative", false, "statement lists purchases as negative amounts")
+	fs.Parse(args)
+
+	if *studentID == "" || *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *delimiter != "" {
+		mapping.Delimiter = []rune(*delimiter)[0]
+	}
+
+	db, err := models.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	user, err := db.GetUserByStudentID(*studentID)
+	if err != nil {
+		log.Fatalf("Failed to look up user: %v", err)
+	}
+	if user == nil {
+		log.Fatalf("No user with student ID %s", *studentID)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	report, err := importer.Run(db, user.ID, f, mapping, *dryRun)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	for _, row := range report.Rows {
+		if row.Status == importer.StatusAccepted {
+			fmt.Printf("line %d: accepted  %s  %8.2f  %s\n", row.Line, row.Date.Format("2006-01-02"), row.Amount, row.Location)
+		} else {
+			fmt.Printf("line %d: rejected  %s\n", row.Line, row.Reason)
+		}
+	}
+
+	fmt.Printf("\n%d rows, %d accepted, %d rejected\n", report.Total, report.Accepted, report.Rejected)
+	if report.DryRun {
+		fmt.Printf("Dry run: no changes applied. Projected balance $%.2f\n", report.Balance)
+	} else if report.Applied {
+		fmt.Printf("Import applied. New balance $%.2f\n", report.Balance)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+)
+
+func runJWTKeygen(args []string) {
+	fs := flag.NewFlagSet("jwt-keygen", flag.ExitOnError)
+	alg := fs.String("alg", "EdDSA", "signing algorithm: EdDSA or RS256")
+	out := fs.String("out", "", "file to write the PEM private key to (required)")
+	fs.Parse(args)
+
+	if *out == "" {
+		log.Fatalf("-out is required")
+	}
+
+	key, err := auth.GenerateSigningKey(*alg)
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+	encoded, err := auth.EncodePrivateKey(key)
+	if err != nil {
+		log.Fatalf("Failed to encode key: %v", err)
+	}
+	kid, err := auth.KeyID(key.Public())
+	if err != nil {
+		log.Fatalf("Failed to compute key ID: %v", err)
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Fatalf("Failed to create key file: %v", err)
+	}
+	if _, err := f.Write(encoded); err != nil {
+		f.Close()
+		log.Fatalf("Failed to write key file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write key file: %v", err)
+	}
+
+	fmt.Printf("Wrote %s signing key %s to %s\n", *alg, kid, *out)
+}
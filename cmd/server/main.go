@@ -1,22 +1,85 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pyne/flexibudget/pkg/api"
 	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/demo"
+	"github.com/pyne/flexibudget/pkg/dining"
+	"github.com/pyne/flexibudget/pkg/mailer"
 	"github.com/pyne/flexibudget/pkg/models"
+	"github.com/pyne/flexibudget/pkg/payments"
 )
 
+const depositPollInterval = 30 * time.Second
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "closeout":
+			runCloseout(os.Args[2:])
+			return
+		case "audit-verify":
+			runAuditVerify(os.Args[2:])
+			return
+		case "fake-idp":
+			runFakeIdP(os.Args[2:])
+			return
+		case "jwt-keygen":
+			runJWTKeygen(os.Args[2:])
+			return
+		case "demo-seed":
+			runDemoSeed(os.Args[2:])
+			return
+		}
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	var keys *auth.Keyring
+	if file := os.Getenv("JWT_SIGNING_KEY"); file != "" {
+		var verifyFiles []string
+		for _, f := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				verifyFiles = append(verifyFiles, f)
+			}
+		}
+		loaded, err := auth.LoadKeyring(file, verifyFiles)
+		if err != nil {
+			log.Fatalf("Failed to load token signing keys: %v", err)
+		}
+		keys = loaded
+	} else if os.Getenv("APP_ENV") == "production" {
+		log.Fatalf("JWT_SIGNING_KEY is required when APP_ENV=production")
+	} else {
+		signingKey, err := auth.GenerateSigningKey("EdDSA")
+		if err == nil {
+			keys, err = auth.NewKeyring(signingKey)
+		}
+		if err != nil {
+			log.Fatalf("Failed to generate token signing key: %v", err)
+		}
+		log.Printf("JWT_SIGNING_KEY is not set; signing tokens with ephemeral key %s", keys.SigningKeyID())
+	}
+	if os.Getenv("JWT_SECRET") != "" {
+		log.Printf("JWT_SECRET is no longer used; configure JWT_SIGNING_KEY instead")
+	}
+	auth.SetKeyring(keys)
+
 	db, err := models.InitDB()
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -29,29 +92,205 @@ func main() {
 	router.Handle("/", fs)
 	router.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
 
-	apiHandler := api.NewHandler(db)
-	authHandler := auth.NewHandler(db)
+	diningStore, err := dining.NewStore(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize dining menu: %v", err)
+	}
+	if rate := os.Getenv("DINING_TAX_RATE"); rate != "" {
+		diningStore.TaxRate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || diningStore.TaxRate < 0 {
+			log.Fatalf("Invalid DINING_TAX_RATE: %q", rate)
+		}
+	}
+	switch policy := os.Getenv("CLOSED_LOCATION_POLICY"); policy {
+	case "":
+	case dining.ClosedWarn, dining.ClosedReject:
+		diningStore.ClosedPolicy = policy
+	default:
+		log.Fatalf("Invalid CLOSED_LOCATION_POLICY: %q", policy)
+	}
+
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "no-reply@flexibudget.local"
+	}
+	var mail mailer.Mailer
+	kind := os.Getenv("MAILER")
+	if os.Getenv("APP_ENV") == "production" && kind != "smtp" {
+		log.Fatalf("MAILER=smtp is required when APP_ENV=production")
+	}
+	switch kind {
+	case "", "log":
+		mail = mailer.NewFileMailer("", mailFrom)
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		mail = mailer.NewFileMailer(dir, mailFrom)
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			log.Fatalf("SMTP_ADDR is required when MAILER=smtp")
+		}
+		mail = mailer.NewSMTPMailer(addr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom)
+	default:
+		log.Fatalf("Invalid MAILER: %q", kind)
+	}
+
+	var provider payments.Provider
+	switch kind := os.Getenv("PAYMENT_PROVIDER"); kind {
+	case "":
+		if os.Getenv("APP_ENV") == "production" {
+			log.Fatalf("PAYMENT_PROVIDER is required when APP_ENV=production")
+		}
+		provider = payments.NewFakeProvider()
+	case "fake":
+		if os.Getenv("APP_ENV") == "production" {
+			log.Fatalf("PAYMENT_PROVIDER=fake cannot be used when APP_ENV=production")
+		}
+		provider = payments.NewFakeProvider()
+	case "none":
+	default:
+		log.Fatalf("Invalid PAYMENT_PROVIDER: %q", kind)
+	}
+
+	apiHandler := api.NewHandler(db, provider, diningStore)
+	go apiHandler.PollPendingDeposits(depositPollInterval)
+	if value := os.Getenv("DEMO_MODE"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid DEMO_MODE: %q", value)
+		}
+		if enabled {
+			result, err := demo.Seed(db, time.Now())
+			if err != nil {
+				log.Fatalf("Failed to start demo mode: %v", err)
+			}
+			log.Printf("Demo mode enabled: %d demo accounts (%d created)", len(result.Accounts), result.Created)
+			apiHandler.DemoBanner = demo.Banner
+		}
+	}
+	authHandler := auth.NewHandler(db, mail)
+	authHandler.ResetURL = os.Getenv("PASSWORD_RESET_URL")
+	if authHandler.ResetURL == "" {
+		authHandler.ResetURL = "http://localhost:" + port + "/reset-password.html"
+	}
+	authHandler.VerifyURL = os.Getenv("EMAIL_VERIFY_URL")
+	if authHandler.VerifyURL == "" {
+		authHandler.VerifyURL = "http://localhost:" + port + "/verify-email.html"
+	}
+	for _, domain := range strings.Split(os.Getenv("EMAIL_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			authHandler.EmailDomains = append(authHandler.EmailDomains, domain)
+		}
+	}
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		config := auth.OIDCConfig{
+			Issuer:         issuer,
+			ClientID:       os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:    os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:         strings.Fields(os.Getenv("OIDC_SCOPES")),
+			StudentIDClaim: os.Getenv("OIDC_STUDENT_ID_CLAIM"),
+		}
+		if config.RedirectURL == "" {
+			config.RedirectURL = "http://localhost:" + port + "/api/auth/oidc/callback"
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		authHandler.OIDC, err = auth.NewOIDCProvider(ctx, config, nil)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to configure single sign-on: %v", err)
+		}
+	}
+	diningHandler := dining.NewHandler(diningStore)
 	
+	router.HandleFunc("/.well-known/jwks.json", keys.ServeJWKS)
 	router.HandleFunc("/api/login", authHandler.Login)
 	router.HandleFunc("/api/register", authHandler.Register)
 	router.HandleFunc("/api/logout", authHandler.Logout)
+	router.HandleFunc("/api/auth/refresh", authHandler.Refresh)
+	router.HandleFunc("/api/auth/password/forgot", authHandler.ForgotPassword)
+	router.HandleFunc("/api/auth/password/reset", authHandler.ResetPassword)
+	router.HandleFunc("/api/auth/email/verify", authHandler.VerifyEmail)
+	router.HandleFunc("/api/auth/2fa/verify", authHandler.VerifyLoginChallenge)
+	router.HandleFunc("/api/auth/oidc", authHandler.OIDCStatus)
+	router.HandleFunc("/api/auth/oidc/login", authHandler.OIDCLogin)
+	router.HandleFunc("/api/auth/oidc/callback", authHandler.OIDCCallback)
 	
-	withAuth := func(handler http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			authMiddleware := auth.AuthMiddleware(db)
-			authMiddleware(http.HandlerFunc(handler)).ServeHTTP(w, r)
+	registered := map[string]bool{}
+	secure := func(path string, handler http.HandlerFunc) {
+		h, err := protect(db, path, handler)
+		if err != nil {
+			log.Fatal(err)
 		}
+		registered[path] = true
+		router.Handle(path, h)
 	}
 	
-	router.HandleFunc("/api/users/me", withAuth(apiHandler.GetCurrentUser))
-	router.HandleFunc("/api/users/me/balance", withAuth(apiHandler.GetUserBalance))
+	secure("/api/auth/logout-all", authHandler.LogoutAll)
+	secure("/api/auth/email/resend", authHandler.ResendVerification)
+	secure("/api/auth/2fa", authHandler.TwoFactorStatus)
+	secure("/api/auth/2fa/setup", authHandler.TwoFactorSetup)
+	secure("/api/auth/2fa/enable", authHandler.TwoFactorEnable)
+	secure("/api/auth/2fa/disable", authHandler.TwoFactorDisable)
+	secure("/api/auth/2fa/recovery-codes", authHandler.TwoFactorRecoveryCodes)
+
+	secure("/api/users/me", apiHandler.GetCurrentUser)
+	secure("/api/users/me/balance", apiHandler.GetUserBalance)
+	secure("/api/users/me/meal-plan", apiHandler.UserMealPlan)
+	secure("/api/users/me/sessions", authHandler.ListSessions)
+	secure("/api/users/me/sessions/", authHandler.RevokeSession)
+
+	secure("/api/meal-plans", apiHandler.GetMealPlans)
+	secure("/api/admin/meal-plans", apiHandler.AdminCreateMealPlan)
+	
+	secure("/api/transactions", apiHandler.GetTransactions)
+	secure("/api/transactions/new", apiHandler.CreateTransaction)
+	secure("/api/transactions/export", apiHandler.ExportTransactions)
+	secure("/api/transactions/import", apiHandler.ImportTransactions)
 	
-	router.HandleFunc("/api/transactions", withAuth(apiHandler.GetTransactions))
-	router.HandleFunc("/api/transactions/new", withAuth(apiHandler.CreateTransaction))
+	secure("/api/budget", apiHandler.GetBudget)
+	secure("/api/budget/update", apiHandler.UpdateBudget)
+
+	secure("/api/statements", apiHandler.GetStatement)
+
+	secure("/api/deposits", apiHandler.GetDeposits)
+	secure("/api/deposits/topup", apiHandler.TopUp)
+	secure("/api/admin/deposits/credit", apiHandler.AdminCredit)
+	secure("/api/admin/deposits/plan-load", apiHandler.AdminPlanLoad)
+	secure("/api/admin/users/roles", authHandler.AdminUserRoles)
+	secure("/api/admin/users", apiHandler.AdminSearchUsers)
+	secure("/api/admin/users/", apiHandler.AdminUser)
+	secure("/api/admin/lockouts", authHandler.AdminLoginLockouts)
+	secure("/api/admin/audit", apiHandler.AdminAuditLog)
+
+	secure("/api/dining/menu", diningHandler.GetMenu)
+	secure("/api/dining/locations", diningHandler.GetLocations)
+	secure("/api/dining/orders", diningHandler.CreateOrder)
+	secure("/api/dining/recommendations", diningHandler.GetRecommendations)
+	secure("/api/dining/preferences", diningHandler.DietaryPreferences)
+	secure("/api/dining/nutrition/goals", diningHandler.NutritionGoals)
+	secure("/api/analytics/nutrition", diningHandler.NutritionAnalytics)
+	secure("/api/admin/dining/discounts", diningHandler.AdminDiscounts)
+	secure("/api/admin/dining/hours", diningHandler.AdminHours)
+	secure("/api/admin/dining/hours/overrides", diningHandler.AdminHoursOverrides)
+	secure("/api/admin/dining/locations", diningHandler.AdminCreateLocation)
+	secure("/api/admin/dining/menu", diningHandler.AdminMenu)
+	secure("/api/admin/dining/menu/", diningHandler.AdminMenuItem)
+	secure("/api/admin/dining/menu/import", diningHandler.AdminImportMenu)
 	
-	router.HandleFunc("/api/budget", withAuth(apiHandler.GetBudget))
-	router.HandleFunc("/api/budget/update", withAuth(apiHandler.UpdateBudget))
+	for path := range routePolicy {
+		if !registered[path] {
+			log.Fatalf("Permission policy lists unregistered route %s", path)
+		}
+	}
+
+	if err := auth.BootstrapAdmins(db); err != nil {
+		log.Fatalf("Failed to grant admin roles: %v", err)
+	}
 	
 	fmt.Printf("Server running at http://localhost:%s/\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, router))
+	log.Fatal(http.ListenAndServe(":"+port, auth.RequestID(router)))
 } 
\ No newline at end of file
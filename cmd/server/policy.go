@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+var routePolicy = auth.Policy{
+	"/api/auth/logout-all":         auth.PermAccount,
+	"/api/auth/email/resend":       auth.PermAccount,
+	"/api/auth/2fa":                auth.PermAccount,
+	"/api/auth/2fa/setup":          auth.PermAccount,
+	"/api/auth/2fa/enable":         auth.PermAccount,
+	"/api/auth/2fa/disable":        auth.PermAccount,
+	"/api/auth/2fa/recovery-codes": auth.PermAccount,
+	"/api/users/me":                auth.PermAccount,
+	"/api/users/me/balance":        auth.PermAccount,
+	"/api/users/me/meal-plan":      auth.PermAccount,
+	"/api/users/me/sessions":       auth.PermAccount,
+	"/api/users/me/sessions/":      auth.PermAccount,
+	"/api/meal-plans":              auth.PermAccount,
+	"/api/transactions":            auth.PermAccount,
+	"/api/transactions/new":        auth.PermAccount,
+	"/api/transactions/export":     auth.PermAccount,
+	"/api/transactions/import":     auth.PermAccount,
+	"/api/budget":                  auth.PermAccount,
+	"/api/budget/update":           auth.PermAccount,
+	"/api/statements":              auth.PermAccount,
+	"/api/deposits":                auth.PermAccount,
+	"/api/deposits/topup":          auth.PermAccountVerified,
+	"/api/dining/menu":             auth.PermAccount,
+	"/api/dining/locations":        auth.PermAccount,
+	"/api/dining/orders":           auth.PermAccountVerified,
+	"/api/dining/recommendations":  auth.PermAccount,
+	"/api/dining/preferences":      auth.PermAccount,
+	"/api/dining/nutrition/goals":  auth.PermAccount,
+	"/api/analytics/nutrition":     auth.PermAccount,
+
+	"/api/admin/dining/discounts":       auth.PermDiningManage,
+	"/api/admin/dining/hours":           auth.PermDiningManage,
+	"/api/admin/dining/hours/overrides": auth.PermDiningManage,
+	"/api/admin/dining/locations":       auth.PermDiningManage,
+	"/api/admin/dining/menu":            auth.PermDiningManage,
+	"/api/admin/dining/menu/":           auth.PermDiningManage,
+	"/api/admin/dining/menu/import":     auth.PermDiningManage,
+	"/api/admin/deposits/credit":        auth.PermDepositsCredit,
+	"/api/admin/deposits/plan-load":     auth.PermDepositsLoad,
+	"/api/admin/meal-plans":             auth.PermMealPlansManage,
+	"/api/admin/users/roles":            auth.PermRolesManage,
+	"/api/admin/users":                  auth.PermUsersManage,
+	"/api/admin/users/":                 auth.PermUsersManage,
+	"/api/admin/lockouts":               auth.PermUsersManage,
+	"/api/admin/audit":                  auth.PermAuditRead,
+}
+
+func protect(db *models.DB, path string, handler http.Handler) (http.Handler, error) {
+	permission, err := routePolicy.Permission(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(path, "/api/admin/") {
+		handler = auth.Audit(db)(handler)
+	}
+	return auth.RequirePermission(db, permission)(handler), nil
+}
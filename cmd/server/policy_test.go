@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const testPassword = "password123"
+
+type policyAccount struct {
+	studentID string
+	role      string
+	verified  bool
+}
+
+var policyAccounts = []policyAccount{
+	{studentID: "20000001", role: auth.RoleStudent, verified: true},
+	{studentID: "20000002", role: auth.RoleStudent, verified: false},
+	{studentID: "20000003", role: auth.RoleStaff, verified: true},
+	{studentID: "20000004", role: auth.RoleAdmin, verified: true},
+}
+
+func newPolicyServer(t *testing.T) (*httptest.Server, *models.DB) {
+	t.Helper()
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "policy.db"))
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	key, err := auth.GenerateSigningKey("EdDSA")
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	keys, err := auth.NewKeyring(key)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	auth.SetKeyring(keys)
+	t.Cleanup(func() { auth.SetKeyring(nil) })
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	router := http.NewServeMux()
+	router.HandleFunc("/api/login", auth.NewHandler(db, nil).Login)
+	for path := range routePolicy {
+		h, err := protect(db, path, ok)
+		if err != nil {
+			t.Fatalf("protect %s: %v", path, err)
+		}
+		router.Handle(path, h)
+	}
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, db
+}
+
+func createPolicyAccount(t *testing.T, db *models.DB, a policyAccount) {
+	t.Helper()
+
+	user, err := db.CreateUser(a.studentID, "Policy "+a.role, a.studentID+"@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("CreateUser %s: %v", a.studentID, err)
+	}
+	if a.role != models.DefaultRole {
+		if _, err := db.GrantUserRole(user.ID, a.role); err != nil {
+			t.Fatalf("GrantUserRole %s: %v", a.studentID, err)
+		}
+	}
+	if a.verified {
+		if err := db.MarkEmailVerified(user.ID, time.Now()); err != nil {
+			t.Fatalf("MarkEmailVerified %s: %v", a.studentID, err)
+		}
+	}
+}
+
+func login(t *testing.T, server *httptest.Server, studentID string) string {
+	t.Helper()
+
+	body := strings.NewReader(`{"student_id":"` + studentID + `","password":"` + testPassword + `"}`)
+	resp, err := http.Post(server.URL+"/api/login", "application/json", body)
+	if err != nil {
+		t.Fatalf("login %s: %v", studentID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login %s: status %d", studentID, resp.StatusCode)
+	}
+
+	var out struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Token == "" {
+		t.Fatalf("login %s: no token (%v)", studentID, err)
+	}
+	return out.Token
+}
+
+func routeStatus(t *testing.T, server *httptest.Server, path, token string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func holds(perms []string, perm string) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRoutePolicy(t *testing.T) {
+	server, db := newPolicyServer(t)
+
+	tokens := map[string]string{}
+	for _, a := range policyAccounts {
+		createPolicyAccount(t, db, a)
+		tokens[a.studentID] = login(t, server, a.studentID)
+	}
+
+	for path, perm := range routePolicy {
+		if got := routeStatus(t, server, path, ""); got != http.StatusUnauthorized {
+			t.Errorf("anonymous GET %s: status %d, want %d", path, got, http.StatusUnauthorized)
+		}
+
+		for _, a := range policyAccounts {
+			perms := auth.PermissionsFor([]string{a.role})
+			allowed := holds(perms, perm) && (a.verified || perm != auth.PermAccountVerified)
+
+			got := routeStatus(t, server, path, tokens[a.studentID])
+			switch {
+			case allowed && got != http.StatusOK:
+				t.Errorf("%s (verified=%v) GET %s [%s]: status %d, want %d", a.role, a.verified, path, perm, got, http.StatusOK)
+			case !allowed && got != http.StatusForbidden:
+				t.Errorf("%s (verified=%v) GET %s [%s]: status %d, want %d", a.role, a.verified, path, perm, got, http.StatusForbidden)
+			}
+		}
+	}
+}
+
+func TestStudentForbiddenOnAdminRoutes(t *testing.T) {
+	server, db := newPolicyServer(t)
+
+	student := policyAccounts[0]
+	createPolicyAccount(t, db, student)
+	token := login(t, server, student.studentID)
+
+	for path := range routePolicy {
+		if !strings.HasPrefix(path, "/api/admin/") {
+			continue
+		}
+		if got := routeStatus(t, server, path, token); got != http.StatusForbidden {
+			t.Errorf("student GET %s: status %d, want %d", path, got, http.StatusForbidden)
+		}
+	}
+}
+
+func TestUnverifiedStudentNeedsVerifiedEmail(t *testing.T) {
+	server, db := newPolicyServer(t)
+
+	student := policyAccount{studentID: "20000009", role: auth.RoleStudent}
+	createPolicyAccount(t, db, student)
+	token := login(t, server, student.studentID)
+
+	checked := 0
+	for path, perm := range routePolicy {
+		want := http.StatusOK
+		if perm != auth.PermAccount {
+			want = http.StatusForbidden
+		}
+		if got := routeStatus(t, server, path, token); got != want {
+			t.Errorf("unverified student GET %s [%s]: status %d, want %d", path, perm, got, want)
+		}
+		if perm == auth.PermAccountVerified {
+			checked++
+		}
+	}
+	if checked == 0 {
+		t.Fatal("routePolicy has no routes requiring a verified email")
+	}
+
+	if err := db.MarkEmailVerified(mustUserID(t, db, student.studentID), time.Now()); err != nil {
+		t.Fatalf("MarkEmailVerified: %v", err)
+	}
+	for path, perm := range routePolicy {
+		if perm != auth.PermAccountVerified {
+			continue
+		}
+		if got := routeStatus(t, server, path, token); got != http.StatusOK {
+			t.Errorf("verified student GET %s: status %d, want %d", path, got, http.StatusOK)
+		}
+	}
+}
+
+func mustUserID(t *testing.T, db *models.DB, studentID string) int64 {
+	t.Helper()
+
+	user, err := db.GetUserByStudentID(studentID)
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByStudentID %s: %v", studentID, err)
+	}
+	return user.ID
+}
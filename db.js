@@ -699,6 +699,14 @@ db.createFairyRequest = async (userId, location, amount, description) => {
   return result.lastID;
 };
 
+async function isEmailVerified(userId) {
+  const row = await get(
+    `SELECT email_verified_at FROM users WHERE id = ?`,
+    [userId]
+  );
+  return Boolean(row && row.email_verified_at);
+}
+
 async function getUserPendingRequests(userId) {
   return all(
     `SELECT * FROM fairy_requests 
@@ -958,6 +966,7 @@ module.exports = {
   createUser,
   getUserById,
   getUserByStudentId,
+  isEmailVerified,
   verifyPassword,
   getUserBalance,
   createTransaction,
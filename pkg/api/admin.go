@@ -0,0 +1,344 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	adminSearchLimit    = 25
+	maxManualAdjustment = 1000.00
+)
+
+func (h *Handler) AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.db.SearchUsers(query, adminSearchLimit)
+	if err != nil {
+		http.Error(w, "Failed to search users", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "user.search", nil, "", map[string]interface{}{"q": query, "results": len(users)})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+}
+
+func (h *Handler) AdminUser(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/"), "/")
+	if parts[0] == "" {
+		http.Error(w, "Student ID is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.db.GetUserByStudentID(parts[0])
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	action := strings.Join(parts[1:], "/")
+	method := http.MethodGet
+	switch action {
+	case "adjustments", "lock", "unlock", "budget/reset", "meal-plan":
+		method = http.MethodPost
+	case "", "balance", "transactions", "budget":
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != method {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch action {
+	case "":
+		h.adminViewUser(w, r, user)
+	case "balance":
+		auth.AnnotateAudit(r.Context(), "user.balance.view", user, "", nil)
+		h.writeBalance(w, user.ID)
+	case "transactions":
+		auth.AnnotateAudit(r.Context(), "user.transactions.view", user, "", nil)
+		h.writeTransactions(w, r.URL.Query(), user.ID)
+	case "budget":
+		h.adminViewBudget(w, r, user)
+	case "budget/reset":
+		h.adminResetBudget(w, r, user)
+	case "adjustments":
+		h.adminAdjustBalance(w, r, user)
+	case "lock":
+		h.adminLockUser(w, r, user)
+	case "unlock":
+		h.adminUnlockUser(w, r, user)
+	case "meal-plan":
+		h.adminChangeMealPlan(w, r, user)
+	}
+}
+
+func (h *Handler) adminViewUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	roles, err := h.db.GetUserRoles(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	var plan *models.UserMealPlan
+	term, err := h.db.GetTermAt(time.Now())
+	if err == nil && term != nil {
+		plan, err = h.db.GetUserMealPlan(user.ID, term.ID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := h.db.ListActiveSessions(user.ID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "user.view", user, "", nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		*models.User
+		Roles          []string             `json:"roles"`
+		Locked         bool                 `json:"locked"`
+		MealPlan       *models.UserMealPlan `json:"meal_plan,omitempty"`
+		ActiveSessions int                  `json:"active_sessions"`
+	}{
+		User:           user,
+		Roles:          roles,
+		Locked:         user.LockedAt != nil,
+		MealPlan:       plan,
+		ActiveSessions: len(sessions),
+	})
+}
+
+func (h *Handler) adminViewBudget(w http.ResponseWriter, r *http.Request, user *models.User) {
+	settings, err := h.db.GetBudgetSettings(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get budget settings", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "user.budget.view", user, "", nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+type adminActionRequest struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+}
+
+func decodeAdminAction(w http.ResponseWriter, r *http.Request) (*adminActionRequest, bool) {
+	var req adminActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return nil, false
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func (h *Handler) adminResetBudget(w http.ResponseWriter, r *http.Request, user *models.User) {
+	req, ok := decodeAdminAction(w, r)
+	if !ok {
+		return
+	}
+
+	before, err := h.db.GetBudgetSettings(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get budget settings", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := h.db.WithAudit(auth.AuditMeta(r)).ResetBudgetSettings(user.ID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to reset budget settings", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "user.budget.reset", user, req.Reason, map[string]interface{}{"before": before, "after": settings})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *Handler) adminAdjustBalance(w http.ResponseWriter, r *http.Request, user *models.User) {
+	req, ok := decodeAdminAction(w, r)
+	if !ok {
+		return
+	}
+
+	amount := math.Round(req.Amount*100) / 100
+	if amount == 0 || math.Abs(amount) > maxManualAdjustment {
+		http.Error(w, "Adjustment must be non-zero and at most $"+strconv.FormatFloat(maxManualAdjustment, 'f', 2, 64), http.StatusBadRequest)
+		return
+	}
+
+	adminID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	adjustment, err := h.db.WithAudit(auth.AuditMeta(r)).CreateManualAdjustment(user.ID, amount, req.Reason, adminID)
+	if err != nil {
+		http.Error(w, "Failed to adjust balance", http.StatusInternalServerError)
+		return
+	}
+
+	balance, err := h.db.GetUserBalance(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "balance.adjust", user, req.Reason, map[string]interface{}{
+		"adjustment_id": adjustment.ID,
+		"amount":        adjustment.Amount,
+		"balance":       balance.CurrentBalance,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"adjustment": adjustment, "current_balance": balance.CurrentBalance})
+}
+
+func (h *Handler) adminLockUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	req, ok := decodeAdminAction(w, r)
+	if !ok {
+		return
+	}
+
+	if adminID, _ := auth.UserIDFrom(r.Context()); adminID == user.ID {
+		http.Error(w, "You cannot lock your own account", http.StatusConflict)
+		return
+	}
+
+	if err := h.db.LockUser(user.ID, req.Reason, time.Now()); err != nil {
+		http.Error(w, "Failed to lock account", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "user.lock", user, req.Reason, nil)
+	h.writeLockState(w, user.ID)
+}
+
+func (h *Handler) adminUnlockUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	req, ok := decodeAdminAction(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.UnlockUser(user.ID); err != nil {
+		http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.db.ClearLoginThrottle(auth.AccountThrottleKey(user.StudentID)); err != nil {
+		http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "user.unlock", user, req.Reason, nil)
+	h.writeLockState(w, user.ID)
+}
+
+func (h *Handler) writeLockState(w http.ResponseWriter, userID int64) {
+	user, err := h.db.GetUserByID(userID)
+	if err != nil || user == nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	throttle, err := h.db.GetLoginThrottle(auth.AccountThrottleKey(user.StudentID))
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+	var loginLockedUntil *time.Time
+	if throttle.Locked(time.Now()) {
+		loginLockedUntil = throttle.LockedUntil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"student_id":         user.StudentID,
+		"locked":             user.LockedAt != nil,
+		"locked_at":          user.LockedAt,
+		"locked_reason":      user.LockedReason,
+		"login_locked_until": loginLockedUntil,
+	})
+}
+
+func (h *Handler) AdminAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.AuditFilter{Action: q.Get("action")}
+	filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+
+	for param, dest := range map[string]*int64{"actor": &filter.ActorID, "student_id": &filter.TargetUserID} {
+		studentID := q.Get(param)
+		if studentID == "" {
+			continue
+		}
+		user, err := h.db.GetUserByStudentID(studentID)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		*dest = user.ID
+	}
+
+	events, err := h.db.ListAuditEvents(filter)
+	if err != nil {
+		http.Error(w, "Failed to get audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+	"github.com/pyne/flexibudget/pkg/payments"
+)
+
+const (
+	minTopUpAmount = 5.00
+	maxTopUpAmount = 500.00
+	chargeTimeout  = 30 * time.Second
+)
+
+func (h *Handler) TopUp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Amount       float64 `json:"amount"`
+		PaymentToken string  `json:"payment_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount < minTopUpAmount || req.Amount > maxTopUpAmount {
+		http.Error(w, "Top-up amount must be between $5.00 and $500.00", http.StatusBadRequest)
+		return
+	}
+
+	if h.payments == nil {
+		http.Error(w, "Top-ups are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if req.PaymentToken == "" {
+		http.Error(w, "Payment token is required", http.StatusBadRequest)
+		return
+	}
+
+	deposit, err := h.db.CreateDeposit(userID, models.DepositSourceTopUp, req.Amount, h.payments.Name(), "", nil)
+	if err != nil {
+		http.Error(w, "Failed to create deposit", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chargeTimeout)
+	result, err := h.payments.Charge(ctx, payments.ChargeRequest{
+		Reference:    deposit.Reference,
+		Amount:       deposit.Amount,
+		Currency:     "USD",
+		Description:  "FlexiBudget meal plan top-up",
+		PaymentToken: req.PaymentToken,
+	})
+	cancel()
+	if err != nil {
+		log.Printf("Charge for deposit %s did not complete; leaving it pending: %v", deposit.Reference, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(deposit)
+		return
+	}
+
+	reference := deposit.Reference
+	deposit, err = applyChargeResult(h.db.WithAudit(auth.AuditMeta(r)), deposit, result)
+	if errors.Is(err, models.ErrDepositNotPending) {
+		deposit, err = h.db.GetDepositByReference(reference)
+	}
+	if err != nil {
+		http.Error(w, "Failed to record deposit", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	if deposit.Status == models.DepositFailed {
+		status = http.StatusPaymentRequired
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(deposit)
+}
+
+func applyChargeResult(db *models.DB, deposit *models.Deposit, result *payments.ChargeResult) (*models.Deposit, error) {
+	switch result.Status {
+	case payments.StatusSettled:
+		return db.SettleDeposit(deposit.Reference, result.ProviderReference)
+	case payments.StatusFailed:
+		return db.FailDeposit(deposit.Reference, result.ProviderReference, result.FailureReason)
+	default:
+		if err := db.SetDepositProviderReference(deposit.Reference, result.ProviderReference); err != nil {
+			return nil, err
+		}
+		return db.GetDepositByReference(deposit.Reference)
+	}
+}
+
+func (h *Handler) GetDeposits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deposits, err := h.db.GetUserDeposits(userID)
+	if err != nil {
+		http.Error(w, "Failed to get deposits", http.StatusInternalServerError)
+		return
+	}
+
+	if deposits == nil {
+		deposits = []models.Deposit{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"deposits": deposits})
+}
+
+func (h *Handler) SettlePendingDeposits(ctx context.Context) (int, error) {
+	deposits, err := h.db.GetPendingDeposits(h.payments.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	var updated int
+	for i := range deposits {
+		result, err := h.payments.Lookup(ctx, deposits[i].Reference)
+		if errors.Is(err, payments.ErrUnknownCharge) {
+			if time.Since(deposits[i].CreatedAt) < chargeTimeout {
+				continue
+			}
+			if _, err := h.db.FailDeposit(deposits[i].Reference, "", "payment provider has no record of the charge"); err != nil && !errors.Is(err, models.ErrDepositNotPending) {
+				return updated, err
+			}
+			updated++
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to look up deposit %s: %v", deposits[i].Reference, err)
+			continue
+		}
+		if result.Status == payments.StatusPending {
+			continue
+		}
+		if _, err := applyChargeResult(h.db, &deposits[i], result); err != nil && !errors.Is(err, models.ErrDepositNotPending) {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func (h *Handler) PollPendingDeposits(interval time.Duration) {
+	if h.payments == nil {
+		return
+	}
+	for range time.Tick(interval) {
+		if _, err := h.SettlePendingDeposits(context.Background()); err != nil {
+			log.Printf("Failed to settle pending deposits: %v", err)
+		}
+	}
+}
+
+func (h *Handler) AdminCredit(w http.ResponseWriter, r *http.Request) {
+	h.adminDeposit(w, r, models.DepositSourceAdminCredit)
+}
+
+func (h *Handler) AdminPlanLoad(w http.ResponseWriter, r *http.Request) {
+	h.adminDeposit(w, r, models.DepositSourcePlanLoad)
+}
+
+func (h *Handler) adminDeposit(w http.ResponseWriter, r *http.Request, source string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		StudentID string  `json:"student_id"`
+		Amount    float64 `json:"amount"`
+		Note      string  `json:"note"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount <= 0 {
+		http.Error(w, "Amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if req.Note == "" {
+		http.Error(w, "Note is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.db.GetUserByStudentID(req.StudentID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	deposit, err := h.db.CreateDeposit(user.ID, source, req.Amount, "", req.Note, &adminID)
+	if err != nil {
+		http.Error(w, "Failed to create deposit", http.StatusInternalServerError)
+		return
+	}
+
+	deposit, err = h.db.WithAudit(auth.AuditMeta(r)).SettleDeposit(deposit.Reference, "")
+	if err != nil {
+		http.Error(w, "Failed to settle deposit", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "deposit."+source, user, req.Note, map[string]interface{}{
+		"reference": deposit.Reference,
+		"amount":    deposit.Amount,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(deposit)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+	"github.com/pyne/flexibudget/pkg/payments"
+)
+
+type lostChargeProvider struct {
+	*payments.FakeProvider
+	charge bool
+}
+
+func (p *lostChargeProvider) Charge(ctx context.Context, req payments.ChargeRequest) (*payments.ChargeResult, error) {
+	if p.charge {
+		p.FakeProvider.Charge(ctx, req)
+	}
+	return nil, context.DeadlineExceeded
+}
+
+func newTestDB(t *testing.T) *models.DB {
+	t.Helper()
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "api.db"))
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestUser(t *testing.T, db *models.DB, studentID string) *models.User {
+	t.Helper()
+
+	user, err := db.CreateUser(studentID, "Test "+studentID, studentID+"@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func topUp(t *testing.T, h *Handler, ctx context.Context, user *models.User, amount string) (int, *models.Deposit) {
+	t.Helper()
+
+	body := strings.NewReader(`{"amount":` + amount + `,"payment_token":"tok_visa"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/deposits/topup", body)
+	req = req.WithContext(auth.WithPrincipal(ctx, &auth.Principal{User: user}))
+	rec := httptest.NewRecorder()
+	h.TopUp(rec, req)
+
+	var deposit models.Deposit
+	if err := json.NewDecoder(rec.Body).Decode(&deposit); err != nil {
+		t.Fatalf("decoding deposit (status %d): %v", rec.Code, err)
+	}
+	return rec.Code, &deposit
+}
+
+func depositStatus(t *testing.T, db *models.DB, reference string) string {
+	t.Helper()
+
+	deposit, err := db.GetDepositByReference(reference)
+	if err != nil || deposit == nil {
+		t.Fatalf("GetDepositByReference: %v", err)
+	}
+	return deposit.Status
+}
+
+func TestTopUpSurvivesClientDisconnect(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "70000001")
+	h := NewHandler(db, payments.NewFakeProvider(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	status, deposit := topUp(t, h, ctx, user, "20")
+	if status != http.StatusCreated || deposit.Status != models.DepositSettled {
+		t.Fatalf("status %d, deposit %s; want %d, settled", status, deposit.Status, http.StatusCreated)
+	}
+}
+
+func TestTopUpLeavesUnconfirmedChargePending(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "70000002")
+	provider := &lostChargeProvider{FakeProvider: payments.NewFakeProvider(), charge: true}
+	h := NewHandler(db, provider, nil)
+
+	before, err := db.GetUserBalance(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, deposit := topUp(t, h, context.Background(), user, "35.55")
+	if status != http.StatusAccepted || deposit.Status != models.DepositPending {
+		t.Fatalf("status %d, deposit %s; want %d, pending", status, deposit.Status, http.StatusAccepted)
+	}
+
+	updated, err := h.SettlePendingDeposits(context.Background())
+	if err != nil || updated != 1 {
+		t.Fatalf("SettlePendingDeposits = %d, %v; want 1", updated, err)
+	}
+	if got := depositStatus(t, db, deposit.Reference); got != models.DepositSettled {
+		t.Fatalf("deposit status = %s, want settled", got)
+	}
+
+	after, err := db.GetUserBalance(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := after.CurrentBalance - before.CurrentBalance; got < 35.549 || got > 35.551 {
+		t.Fatalf("balance change = %.2f, want 35.55", got)
+	}
+}
+
+func TestSettlePendingFailsChargesUnknownToProvider(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "70000003")
+	provider := &lostChargeProvider{FakeProvider: payments.NewFakeProvider()}
+	h := NewHandler(db, provider, nil)
+
+	_, deposit := topUp(t, h, context.Background(), user, "10")
+
+	if _, err := h.SettlePendingDeposits(context.Background()); err != nil {
+		t.Fatalf("SettlePendingDeposits: %v", err)
+	}
+	if got := depositStatus(t, db, deposit.Reference); got != models.DepositPending {
+		t.Fatalf("deposit status = %s, want pending while the charge may be in flight", got)
+	}
+
+	if _, err := db.Exec(`UPDATE deposits SET created_at = ? WHERE reference = ?`, time.Now().Add(-2*chargeTimeout), deposit.Reference); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.SettlePendingDeposits(context.Background()); err != nil {
+		t.Fatalf("SettlePendingDeposits: %v", err)
+	}
+	if got := depositStatus(t, db, deposit.Reference); got != models.DepositFailed {
+		t.Fatalf("deposit status = %s, want failed", got)
+	}
+}
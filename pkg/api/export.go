@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/export"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const exportFlushEvery = 100
+
+func (h *Handler) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := auth.UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID := user.ID
+
+	q := r.URL.Query()
+	formatName := q.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+
+	format, err := export.Lookup(formatName)
+	if err != nil {
+		http.Error(w, "Unsupported format, expected csv, ofx or json", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseTransactionFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	balance, err := h.exportBalance(userID, filter)
+	if err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case h.exportSlots <- struct{}{}:
+		defer func() { <-h.exportSlots }()
+	default:
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Too many exports in progress, try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+
+	filename := fmt.Sprintf("flexibudget-transactions-%s-%s.%s", user.StudentID, time.Now().Format("20060102"), format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	flusher, _ := w.(http.Flusher)
+	out := format.New(w, export.Account{
+		StudentID: user.StudentID,
+		Name:      user.Name,
+		Balance:   balance,
+		From:      filter.From,
+		To:        filter.To,
+	})
+
+	if err := out.Begin(); err != nil {
+		log.Printf("Export for user %d failed: %v", userID, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	count := 0
+	err = h.db.EachUserTransaction(r.Context(), userID, filter, func(tx *models.Transaction) error {
+		if err := out.Write(tx); err != nil {
+			return err
+		}
+		count++
+		if flusher != nil && count%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = out.End()
+	}
+	if err != nil {
+		log.Printf("Export for user %d failed after %d transactions: %v", userID, count, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h *Handler) exportBalance(userID int64, filter models.TransactionFilter) (float64, error) {
+	if filter.To.IsZero() {
+		balance, err := h.db.GetUserBalance(userID)
+		if err != nil {
+			return 0, err
+		}
+		return balance.CurrentBalance, nil
+	}
+	return h.db.GetBalanceAt(userID, filter.To)
+}
+
+func parseTransactionFilter(q url.Values) (models.TransactionFilter, error) {
+	var filter models.TransactionFilter
+
+	if from := q.Get("from"); from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid from date, expected YYYY-MM-DD")
+		}
+		filter.From = t
+	}
+
+	if to := q.Get("to"); to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid to date, expected YYYY-MM-DD")
+		}
+		filter.To = t.AddDate(0, 0, 1)
+	}
+
+	filter.Location = q.Get("location")
+
+	if min := q.Get("min_amount"); min != "" {
+		v, err := strconv.ParseFloat(min, 64)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid min_amount")
+		}
+		filter.MinAmount = &v
+	}
+
+	if max := q.Get("max_amount"); max != "" {
+		v, err := strconv.ParseFloat(max, 64)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid max_amount")
+		}
+		filter.MaxAmount = &v
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func exportBody(t *testing.T, h *Handler, user *models.User, query string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/transactions/export?"+query, nil)
+	req = req.WithContext(auth.WithPrincipal(context.Background(), &auth.Principal{User: user}))
+	rec := httptest.NewRecorder()
+	h.ExportTransactions(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export %s: status %d: %s", query, rec.Code, rec.Body.String())
+	}
+	return rec.Body.String()
+}
+
+func TestExportAmountFilters(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "70000010")
+	h := NewHandler(db, nil, nil)
+
+	at := time.Now().Add(-time.Hour)
+	txs := []models.Transaction{
+		{Amount: 12.50, Location: "Market Cafe", Description: "Lunch", TransactionDate: at},
+		{Amount: -4.25, Location: "Market Cafe", Description: "Refund", TransactionDate: at.Add(time.Minute)},
+		{Amount: 0, Location: "Market Cafe", Description: "Voided", TransactionDate: at.Add(2 * time.Minute)},
+	}
+	if _, _, err := db.ImportTransactions(user.ID, txs); err != nil {
+		t.Fatalf("ImportTransactions: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"format=csv", []string{"12.50", "-4.25", "0.00"}},
+		{"format=csv&min_amount=0", []string{"12.50", "0.00"}},
+		{"format=csv&max_amount=0", []string{"-4.25", "0.00"}},
+		{"format=csv&min_amount=0&max_amount=0", []string{"0.00"}},
+	}
+	for _, tt := range tests {
+		records, err := csv.NewReader(strings.NewReader(exportBody(t, h, user, tt.query))).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: reading csv: %v", tt.query, err)
+		}
+		var got []string
+		for _, record := range records[1:] {
+			got = append(got, record[3])
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: amounts = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExportOFXLedgerBalance(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "70000011")
+	h := NewHandler(db, nil, nil)
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	txs := []models.Transaction{
+		{Amount: 20.10, Location: "Market Cafe", Description: "Lunch", TransactionDate: yesterday},
+		{Amount: 5.05, Location: "Café Bleu", Description: "Coffee", TransactionDate: time.Now()},
+	}
+	if _, _, err := db.ImportTransactions(user.ID, txs); err != nil {
+		t.Fatalf("ImportTransactions: %v", err)
+	}
+	balance, err := db.GetUserBalance(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserBalance: %v", err)
+	}
+	asOfYesterday := balance.CurrentBalance + 5.05
+
+	tests := []struct {
+		query string
+		want  float64
+	}{
+		{"format=ofx", balance.CurrentBalance},
+		{"format=ofx&location=Market+Cafe", balance.CurrentBalance},
+		{"format=ofx&to=" + yesterday.Format("2006-01-02"), asOfYesterday},
+	}
+	for _, tt := range tests {
+		body := exportBody(t, h, user, tt.query)
+		if !strings.Contains(body, "ENCODING:UTF-8\nCHARSET:NONE\n") {
+			t.Errorf("%s: header does not declare UTF-8", tt.query)
+		}
+		want := "<LEDGERBAL>\n<BALAMT>" + strconv.FormatFloat(tt.want, 'f', 2, 64) + "\n"
+		if !strings.Contains(body, want) {
+			t.Errorf("%s: missing %q in\n%s", tt.query, want, body)
+		}
+	}
+}
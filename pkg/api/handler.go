@@ -2,20 +2,40 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/dining"
 	"github.com/pyne/flexibudget/pkg/models"
+	"github.com/pyne/flexibudget/pkg/payments"
 )
 
+const maxConcurrentExports = 2
+
 type Handler struct {
-	db *models.DB
+	db          *models.DB
+	payments    payments.Provider
+	dining      *dining.Store
+	exportSlots chan struct{}
+	DemoBanner  string
 }
 
-func NewHandler(db *models.DB) *Handler {
-	return &Handler{db: db}
+type transactionResponse struct {
+	*models.Transaction
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func NewHandler(db *models.DB, provider payments.Provider, diningStore *dining.Store) *Handler {
+	return &Handler{
+		db:          db,
+		payments:    provider,
+		dining:      diningStore,
+		exportSlots: make(chan struct{}, maxConcurrentExports),
+	}
 }
 
 func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
@@ -24,28 +44,41 @@ func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ExtractUserID(r)
-	if err != nil {
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	user := principal.User
 
-	user, err := h.db.GetUserByID(userID)
-	if err != nil || user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	enrollment, err := h.db.GetTOTP(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
 		return
 	}
 
 	userResponse := struct {
-		ID        int64  `json:"id"`
-		StudentID string `json:"student_id"`
-		Name      string `json:"name"`
-		Email     string `json:"email"`
+		ID            int64    `json:"id"`
+		StudentID     string   `json:"student_id"`
+		Name          string   `json:"name"`
+		Email         string   `json:"email"`
+		EmailVerified bool     `json:"email_verified"`
+		TwoFactor     bool     `json:"two_factor_enabled"`
+		Roles         []string `json:"roles"`
+		Permissions   []string `json:"permissions"`
+		Demo          bool     `json:"demo"`
+		DemoBanner    string   `json:"demo_banner,omitempty"`
 	}{
-		ID:        user.ID,
-		StudentID: user.StudentID,
-		Name:      user.Name,
-		Email:     user.Email,
+		ID:            user.ID,
+		StudentID:     user.StudentID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		TwoFactor:     enrollment != nil && enrollment.EnabledAt != nil,
+		Roles:         principal.Roles,
+		Permissions:   principal.Permissions,
+		Demo:          h.DemoBanner != "",
+		DemoBanner:    h.DemoBanner,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -58,31 +91,93 @@ func (h *Handler) GetUserBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ExtractUserID(r)
-	if err != nil {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	h.writeBalance(w, userID)
+}
+
+func (h *Handler) writeBalance(w http.ResponseWriter, userID int64) {
+	if err := h.db.ResetSwipesIfDue(userID, time.Now()); err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
 	balance, err := h.db.GetUserBalance(userID)
 	if err != nil {
 		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
 		return
 	}
 
-	spentAmount := balance.StartingBalance - balance.CurrentBalance
+	weekStart, weekEnd := models.WeekBounds(time.Now())
+	swipesThisWeek, err := h.db.GetSwipesUsedBetween(userID, weekStart, weekEnd)
+	if err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
+	deposited, err := h.db.GetSettledDepositTotal(userID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
+	adjusted, err := h.db.GetAdjustmentTotal(userID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
+	spentAmount := balance.StartingBalance + deposited + adjusted - balance.CurrentBalance
+
+	type dollarAsset struct {
+		Starting  float64 `json:"starting"`
+		Current   float64 `json:"current"`
+		Deposited float64 `json:"deposited"`
+		Spent     float64 `json:"spent"`
+	}
+
+	type swipeAsset struct {
+		Remaining    int        `json:"remaining"`
+		Allowance    int        `json:"allowance"`
+		Reset        string     `json:"reset"`
+		ResetsAt     *time.Time `json:"resets_at,omitempty"`
+		UsedThisWeek int        `json:"used_this_week"`
+	}
 
 	balanceResponse := struct {
 		UserID          int64   `json:"user_id"`
 		StartingBalance float64 `json:"starting_balance"`
 		CurrentBalance  float64 `json:"current_balance"`
+		DepositedAmount float64 `json:"deposited_amount"`
 		SpentAmount     float64 `json:"spent_amount"`
+		Assets          struct {
+			Dollars dollarAsset `json:"dollars"`
+			Swipes  swipeAsset  `json:"swipes"`
+		} `json:"assets"`
 	}{
 		UserID:          balance.UserID,
 		StartingBalance: balance.StartingBalance,
 		CurrentBalance:  balance.CurrentBalance,
+		DepositedAmount: deposited,
 		SpentAmount:     spentAmount,
 	}
+	balanceResponse.Assets.Dollars = dollarAsset{
+		Starting:  balance.StartingBalance,
+		Current:   balance.CurrentBalance,
+		Deposited: deposited,
+		Spent:     spentAmount,
+	}
+	balanceResponse.Assets.Swipes = swipeAsset{
+		Remaining:    balance.SwipesRemaining,
+		Allowance:    balance.SwipeAllowance,
+		Reset:        balance.SwipeReset,
+		ResetsAt:     balance.SwipesResetAt,
+		UsedThisWeek: swipesThisWeek,
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(balanceResponse)
@@ -94,13 +189,16 @@ func (h *Handler) GetTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ExtractUserID(r)
-	if err != nil {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	q := r.URL.Query()
+	h.writeTransactions(w, r.URL.Query(), userID)
+}
+
+func (h *Handler) writeTransactions(w http.ResponseWriter, q url.Values, userID int64) {
 	limit, _ := strconv.Atoi(q.Get("limit"))
 	offset, _ := strconv.Atoi(q.Get("offset"))
 	
@@ -120,8 +218,23 @@ func (h *Handler) GetTransactions(w http.ResponseWriter, r *http.Request) {
 		Amount          float64   `json:"amount"`
 		Location        string    `json:"location"`
 		Description     string    `json:"description"`
+		Asset           string    `json:"asset"`
 		TransactionDate time.Time `json:"transaction_date"`
 		Icon            string    `json:"icon"`
+		Items           []models.TransactionItem `json:"items,omitempty"`
+	}
+
+	var items map[int64][]models.TransactionItem
+	if q.Get("expand") == "items" {
+		ids := make([]int64, len(transactions))
+		for i, tx := range transactions {
+			ids[i] = tx.ID
+		}
+		items, err = h.db.GetTransactionItems(ids)
+		if err != nil {
+			http.Error(w, "Failed to get transaction items", http.StatusInternalServerError)
+			return
+		}
 	}
 
 	locationIcons := map[string]string{
@@ -147,8 +260,10 @@ func (h *Handler) GetTransactions(w http.ResponseWriter, r *http.Request) {
 			Amount:          tx.Amount,
 			Location:        tx.Location,
 			Description:     tx.Description,
+			Asset:           tx.Asset,
 			TransactionDate: tx.TransactionDate,
 			Icon:            icon,
+			Items:           items[tx.ID],
 		})
 	}
 
@@ -174,16 +289,18 @@ func (h *Handler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ExtractUserID(r)
-	if err != nil {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req struct {
-		Amount      float64 `json:"amount"`
-		Location    string  `json:"location"`
-		Description string  `json:"description"`
+		Amount        float64 `json:"amount"`
+		Location      string  `json:"location"`
+		Description   string  `json:"description"`
+		Asset         string  `json:"asset"`
+		ConfirmClosed bool    `json:"confirm_closed"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -201,12 +318,38 @@ func (h *Handler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var warnings []string
+	warning, err := h.dining.CheckLocationOpen(req.Location, time.Now())
+	switch {
+	case errors.Is(err, dining.ErrLocationClosed):
+		http.Error(w, warning, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to check location hours", http.StatusInternalServerError)
+		return
+	case warning != "" && !req.ConfirmClosed:
+		http.Error(w, warning+"; resubmit with confirm_closed to record it anyway", http.StatusConflict)
+		return
+	case warning != "":
+		warnings = append(warnings, warning)
+	}
+
 	settings, err := h.db.GetBudgetSettings(userID)
 	if err != nil {
 		http.Error(w, "Failed to get budget settings", http.StatusInternalServerError)
 		return
 	}
 
+	switch req.Asset {
+	case "", models.AssetDollars:
+	case models.AssetSwipes:
+		h.createSwipeTransaction(w, r, userID, settings, req.Amount, req.Location, req.Description, warnings)
+		return
+	default:
+		http.Error(w, "Asset must be dollars or swipes", http.StatusBadRequest)
+		return
+	}
+
 	balance, err := h.db.GetUserBalance(userID)
 	if err != nil {
 		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
@@ -218,14 +361,18 @@ func (h *Handler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := h.db.CreateTransaction(userID, req.Amount, req.Location, req.Description)
+	tx, err := h.db.WithAudit(auth.AuditMeta(r)).CreateTransaction(userID, req.Amount, req.Location, req.Description)
+	if errors.Is(err, models.ErrTermClosed) {
+		http.Error(w, "The current term is closed to new transactions", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tx)
+	json.NewEncoder(w).Encode(transactionResponse{tx, warnings})
 }
 
 func (h *Handler) GetBudget(w http.ResponseWriter, r *http.Request) {
@@ -234,8 +381,8 @@ func (h *Handler) GetBudget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ExtractUserID(r)
-	if err != nil {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -256,8 +403,8 @@ func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ExtractUserID(r)
-	if err != nil {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -275,7 +422,12 @@ func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.UpdateBudgetSettings(&req); err != nil {
+	if req.WeeklySwipeLimit < 0 {
+		http.Error(w, "Weekly swipe limit must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.WithAudit(auth.AuditMeta(r)).UpdateBudgetSettings(&req); err != nil {
 		http.Error(w, "Failed to update budget settings", http.StatusInternalServerError)
 		return
 	}
@@ -288,4 +440,39 @@ func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(settings)
-} 
\ No newline at end of file
+}
+
+func (h *Handler) createSwipeTransaction(w http.ResponseWriter, r *http.Request, userID int64, settings *models.BudgetSettings, amount float64, location, description string, warnings []string) {
+	swipes := int(amount)
+	if float64(swipes) != amount {
+		http.Error(w, "Swipe amount must be a whole number", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if err := h.db.ResetSwipesIfDue(userID, now); err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.db.WithAudit(auth.AuditMeta(r)).UseSwipes(userID, swipes, location, description, settings.WeeklySwipeLimit)
+	if errors.Is(err, models.ErrInsufficientSwipes) {
+		http.Error(w, "Not enough meal swipes remaining", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, models.ErrWeeklySwipeLimit) {
+		http.Error(w, "Transaction exceeds weekly swipe limit", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, models.ErrTermClosed) {
+		http.Error(w, "The current term is closed to new transactions", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transactionResponse{tx, warnings})
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/importer"
+)
+
+const maxImportSize = 10 << 20
+
+func (h *Handler) ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var body io.Reader = r.Body
+	param := r.URL.Query().Get
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportSize); err != nil {
+			http.Error(w, "Invalid upload", http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		param = r.FormValue
+	}
+
+	mapping := importer.DefaultMapping()
+	if v := param("date_column"); v != "" {
+		mapping.DateColumn = v
+	}
+	if v := param("amount_column"); v != "" {
+		mapping.AmountColumn = v
+	}
+	if v := param("location_column"); v != "" {
+		mapping.LocationColumn = v
+	}
+	if v := param("description_column"); v != "" {
+		mapping.DescriptionColumn = v
+	}
+	mapping.DateFormat = param("date_format")
+	if v := param("delimiter"); v != "" {
+		mapping.Delimiter = []rune(v)[0]
+	}
+	mapping.DebitsNegative, _ = strconv.ParseBool(param("debits_negative"))
+	dryRun, _ := strconv.ParseBool(param("dry_run"))
+
+	report, err := importer.Run(h.db.WithAudit(auth.AuditMeta(r)), userID, body, mapping, dryRun)
+	if errors.Is(err, importer.ErrInvalidCSV) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to import transactions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
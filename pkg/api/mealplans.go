@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func (h *Handler) GetMealPlans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	plans, err := h.db.GetMealPlans(true)
+	if err != nil {
+		http.Error(w, "Failed to get meal plans", http.StatusInternalServerError)
+		return
+	}
+
+	if plans == nil {
+		plans = []models.MealPlan{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"meal_plans": plans})
+}
+
+func (h *Handler) UserMealPlan(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getUserMealPlan(w, r)
+	case http.MethodPost, http.MethodPut:
+		http.Error(w, "Meal plan changes must be made by dining services", http.StatusForbidden)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) getUserMealPlan(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	term, err := h.db.EnsureTerm(time.Now())
+	if err != nil {
+		http.Error(w, "Failed to get current term", http.StatusInternalServerError)
+		return
+	}
+
+	assignment, err := h.db.GetUserMealPlan(userID, term.ID)
+	if err != nil {
+		http.Error(w, "Failed to get meal plan", http.StatusInternalServerError)
+		return
+	}
+
+	if assignment == nil {
+		plan, err := h.db.GetDefaultMealPlan(term.Kind)
+		if err != nil {
+			http.Error(w, "No meal plan assigned", http.StatusNotFound)
+			return
+		}
+		assignment = &models.UserMealPlan{
+			UserID:        userID,
+			Term:          *term,
+			MealPlan:      *plan,
+			EffectiveFrom: term.StartsAt,
+		}
+	}
+
+	response := struct {
+		*models.UserMealPlan
+		SuggestedWeeklyBudget float64 `json:"suggested_weekly_budget"`
+	}{
+		UserMealPlan:          assignment,
+		SuggestedWeeklyBudget: models.DefaultWeeklyBudget(&assignment.MealPlan, &assignment.Term),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) adminChangeMealPlan(w http.ResponseWriter, r *http.Request, user *models.User) {
+	adminID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		MealPlanID int64  `json:"meal_plan_id"`
+		Reason     string `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Reason == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	assignment, adjustment, err := h.db.WithAudit(auth.AuditMeta(r)).ChangeMealPlan(user.ID, req.MealPlanID, time.Now(), &adminID)
+	switch {
+	case errors.Is(err, models.ErrMealPlanUnavailable):
+		http.Error(w, "Meal plan is not available for the current term", http.StatusBadRequest)
+		return
+	case errors.Is(err, models.ErrSameMealPlan):
+		http.Error(w, "Already on this meal plan", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to change meal plan", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "user.meal_plan.change", user, req.Reason, map[string]interface{}{
+		"meal_plan_id": req.MealPlanID,
+	})
+
+	response := struct {
+		MealPlan   *models.UserMealPlan      `json:"meal_plan"`
+		Adjustment *models.BalanceAdjustment `json:"adjustment,omitempty"`
+	}{
+		MealPlan:   assignment,
+		Adjustment: adjustment,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) AdminCreateMealPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.MealPlan
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Price < 0 || req.DiningDollars < 0 || req.MealSwipes < 0 {
+		http.Error(w, "Price, dining dollars and meal swipes must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if req.Term != models.TermKindSemester && req.Term != models.TermKindSummer {
+		http.Error(w, "Term must be semester or summer", http.StatusBadRequest)
+		return
+	}
+
+	if req.SwipeReset != "" && req.SwipeReset != models.SwipeResetWeekly && req.SwipeReset != models.SwipeResetTerm {
+		http.Error(w, "Swipe reset must be weekly or term", http.StatusBadRequest)
+		return
+	}
+
+	switch req.CloseoutRule {
+	case "", models.CloseoutForfeit, models.CloseoutRollover, models.CloseoutConvert:
+	default:
+		http.Error(w, "Closeout rule must be forfeit, rollover or convert", http.StatusBadRequest)
+		return
+	}
+
+	if req.RolloverCap < 0 || req.ConversionRate < 0 {
+		http.Error(w, "Rollover cap and conversion rate must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.db.CreateMealPlan(&req)
+	if err != nil {
+		http.Error(w, "Failed to create meal plan", http.StatusInternalServerError)
+		return
+	}
+
+	auth.AnnotateAudit(r.Context(), "meal_plan.create", nil, "", plan)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/statements"
+)
+
+func (h *Handler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	month := q.Get("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+
+	start, err := statements.ParseMonth(month)
+	if err != nil {
+		http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	stmt, err := statements.Generate(h.db, userID, start)
+	if err != nil {
+		http.Error(w, "Failed to generate statement", http.StatusInternalServerError)
+		return
+	}
+
+	var body []byte
+	var contentType, ext string
+	switch q.Get("format") {
+	case "", "pdf":
+		body, err = stmt.RenderPDF()
+		contentType, ext = "application/pdf", "pdf"
+	case "html":
+		body, err = stmt.RenderHTML()
+		contentType, ext = "text/html; charset=utf-8", "html"
+	default:
+		http.Error(w, "Unsupported format, expected pdf or html", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to render statement", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	filename := fmt.Sprintf("flexibudget-statement-%s-%s.%s", stmt.StudentID, stmt.Month(), ext)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("X-Checksum-SHA256", hex.EncodeToString(sum[:]))
+	w.Write(body)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/dining"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func createTransaction(t *testing.T, h *Handler, user *models.User, body string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/transactions/new", strings.NewReader(body))
+	req = req.WithContext(auth.WithPrincipal(context.Background(), &auth.Principal{User: user}))
+	rec := httptest.NewRecorder()
+	h.CreateTransaction(rec, req)
+	return rec.Code
+}
+
+func TestCreateTransactionAtClosedLocation(t *testing.T) {
+	db := newTestDB(t)
+	store, err := dining.NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	closed := &dining.HoursOverride{Location: "Market Cafe", Date: time.Now().Format("2006-01-02"), Closed: true, Reason: "Maintenance"}
+	if _, err := store.CreateOverride(closed); err != nil {
+		t.Fatalf("CreateOverride: %v", err)
+	}
+	h := NewHandler(db, nil, store)
+
+	tests := []struct {
+		policy  string
+		confirm bool
+		want    int
+	}{
+		{dining.ClosedWarn, false, http.StatusConflict},
+		{dining.ClosedWarn, true, http.StatusOK},
+		{dining.ClosedReject, false, http.StatusConflict},
+		{dining.ClosedReject, true, http.StatusConflict},
+	}
+	for i, tt := range tests {
+		store.ClosedPolicy = tt.policy
+		user := newTestUser(t, db, "7000002"+string(rune('0'+i)))
+		body := `{"amount":9.75,"location":"Market Cafe","confirm_closed":false}`
+		if tt.confirm {
+			body = strings.Replace(body, "false", "true", 1)
+		}
+
+		if got := createTransaction(t, h, user, body); got != tt.want {
+			t.Errorf("policy %s, confirm %v: status %d, want %d", tt.policy, tt.confirm, got, tt.want)
+		}
+		txs, _, err := db.GetUserTransactions(user.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetUserTransactions: %v", err)
+		}
+		if recorded := len(txs) > 0; recorded != (tt.want == http.StatusOK) {
+			t.Errorf("policy %s, confirm %v: recorded = %v", tt.policy, tt.confirm, recorded)
+		}
+	}
+}
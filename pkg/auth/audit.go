@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func Audit(db *models.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			meta := AuditMeta(r)
+			event := &models.AuditEvent{
+				ActorID:   meta.ActorID,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				IPAddress: meta.IPAddress,
+				RequestID: meta.RequestID,
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), auditEventKey, event)))
+
+			event.Status = rec.status
+			if event.Action == "" {
+				event.Action = r.Method + " " + r.URL.Path
+			}
+			if err := db.RecordAuditEvent(event); err != nil {
+				log.Printf("Failed to record audit event %s: %v", event.Action, err)
+			}
+		})
+	}
+}
+
+func AnnotateAudit(ctx context.Context, action string, target *models.User, reason string, details interface{}) {
+	event, ok := ctx.Value(auditEventKey).(*models.AuditEvent)
+	if !ok {
+		return
+	}
+
+	event.Action = action
+	event.Reason = reason
+	if target != nil {
+		event.TargetUserID = &target.ID
+	}
+	if details != nil {
+		if b, err := json.Marshal(details); err == nil {
+			event.Details = b
+		}
+	}
+}
+
+func AuditMeta(r *http.Request) models.AuditMeta {
+	meta := models.AuditMeta{IPAddress: clientIP(r), RequestID: RequestIDFrom(r.Context())}
+	if principal, ok := PrincipalFrom(r.Context()); ok {
+		meta.ActorID = &principal.User.ID
+	}
+	return meta
+}
+
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			b := make([]byte, 12)
+			if _, err := rand.Read(b); err != nil {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			id = "req_" + hex.EncodeToString(b)
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) recordAuthEvent(r *http.Request, action string, actor, target *models.User, details interface{}) {
+	event := &models.AuditEvent{
+		Action:    action,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		IPAddress: clientIP(r),
+		RequestID: RequestIDFrom(r.Context()),
+	}
+	if actor != nil {
+		event.ActorID = &actor.ID
+	}
+	if target != nil {
+		event.TargetUserID = &target.ID
+	}
+	if details != nil {
+		if b, err := json.Marshal(details); err == nil {
+			event.Details = b
+		}
+	}
+	if err := h.db.RecordAuditEvent(event); err != nil {
+		log.Printf("Failed to record audit event %s: %v", action, err)
+	}
+}
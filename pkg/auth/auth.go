@@ -1,40 +1,67 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/pyne/flexibudget/pkg/mailer"
 	"github.com/pyne/flexibudget/pkg/models"
+	"github.com/pyne/flexibudget/pkg/totp"
 )
 
 type Handler struct {
-	db *models.DB
+	db           *models.DB
+	mailer       mailer.Mailer
+	ResetURL     string
+	VerifyURL    string
+	EmailDomains []string
+	TOTP         *totp.TOTP
+	OIDC         *OIDCProvider
+	dummyHash    string
+	mailJobs     chan func()
 }
 
-func NewHandler(db *models.DB) *Handler {
-	return &Handler{db: db}
+func NewHandler(db *models.DB, m mailer.Mailer) *Handler {
+	h := &Handler{db: db, mailer: m, TOTP: totp.New(), dummyHash: dummyPasswordHash(), mailJobs: make(chan func(), mailQueueSize)}
+	for i := 0; i < mailWorkers; i++ {
+		go h.runMailJobs()
+	}
+	return h
 }
 
 type LoginRequest struct {
 	StudentID string `json:"student_id"`
 	Password  string `json:"password"`
+	LinkToken string `json:"link_token,omitempty"`
 }
 
 type LoginResponse struct {
-	Token     string `json:"token"`
-	ExpiresAt int64  `json:"expires_at"`
-	User      User   `json:"user"`
+	Token            string `json:"token"`
+	ExpiresAt        int64  `json:"expires_at"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at"`
+	User             User   `json:"user"`
 }
 
 type User struct {
-	ID        int64  `json:"id"`
-	StudentID string `json:"student_id"`
-	Name      string `json:"name"`
+	ID            int64    `json:"id"`
+	StudentID     string   `json:"student_id"`
+	Name          string   `json:"name"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	Permissions   []string `json:"permissions"`
 }
 
 type RegisterRequest struct {
@@ -56,6 +83,17 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	keys := loginThrottleKeys(r, req.StudentID)
+	wait, err := h.loginRetryAfter(keys, time.Now())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeRetryAfter(w, wait)
+		return
+	}
+
 	user, err := h.db.GetUserByStudentID(req.StudentID)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -63,40 +101,63 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if user == nil {
-		if os.Getenv("APP_ENV") == "development" && req.StudentID != "" {
-			user, err = h.db.CreateUser(req.StudentID, "Demo User", req.StudentID+"@example.com", req.Password)
-			if err != nil {
-				http.Error(w, "Failed to create user", http.StatusInternalServerError)
-				return
-			}
-		} else {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		h.db.VerifyPassword(&models.User{PasswordHash: h.dummyHash}, req.Password)
+		h.loginFailed(r, keys, nil, req.StudentID, "unknown_user")
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if !h.db.VerifyPassword(user, req.Password) {
+		h.loginFailed(r, keys, user, req.StudentID, "bad_password")
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if user.LockedAt != nil {
+		h.recordAuthEvent(r, "login.failure", nil, user, map[string]string{"student_id": req.StudentID, "reason": "account_locked"})
+		http.Error(w, "Account is locked", http.StatusForbidden)
+		return
+	}
+
+	if req.LinkToken != "" {
+		err := h.db.WithAudit(AuditMeta(r)).ConfirmIdentityLink(hashToken(req.LinkToken), user.ID, time.Now())
+		if err == models.ErrIdentityLinkInvalid {
+			http.Error(w, "Single sign-on link has expired", http.StatusBadRequest)
 			return
 		}
-	} else {
-		if !h.db.VerifyPassword(user, req.Password) {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	expiresAt := time.Now().Add(24 * time.Hour).Unix()
-	token, err := generateToken(user.ID, user.StudentID, expiresAt)
+	enrollment, err := h.enabledTOTP(user.ID)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if enrollment != nil {
+		challenge, err := h.createChallenge(r, user)
+		if err != nil {
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(challenge)
 		return
 	}
 
-	resp := LoginResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
-		User: User{
-			ID:        user.ID,
-			StudentID: user.StudentID,
-			Name:      user.Name,
-		},
+	if _, err := h.db.ClearLoginThrottle(AccountThrottleKey(user.StudentID)); err != nil {
+		log.Printf("Failed to clear login throttle for user %d: %v", user.ID, err)
 	}
 
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	h.recordAuthEvent(r, "login.success", user, user, nil)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -118,6 +179,12 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.Email = strings.TrimSpace(req.Email)
+	if err := h.validateEmail(req.Email); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	existingUser, err := h.db.GetUserByStudentID(req.StudentID)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -135,23 +202,18 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expiresAt := time.Now().Add(24 * time.Hour).Unix()
-	token, err := generateToken(user.ID, user.StudentID, expiresAt)
+	h.recordAuthEvent(r, "register", user, user, nil)
+
+	if err := h.sendEmailVerification(user, AuditMeta(r)); err != nil {
+		log.Printf("Verification email for user %d failed: %v", user.ID, err)
+	}
+
+	resp, err := h.startSession(r, user)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	resp := LoginResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
-		User: User{
-			ID:        user.ID,
-			StudentID: user.StudentID,
-			Name:      user.Name,
-		},
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -162,82 +224,337 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var actor *models.User
+	if claims, err := parseClaims(r); err == nil && claims.SessionID != "" {
+		if err := h.db.RevokeSession(claims.SessionID, models.SessionRevokedLogout); err != nil {
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+		actor = &models.User{ID: claims.UserID}
+	}
+
+	if req.RefreshToken != "" {
+		if err := h.db.RevokeSessionByRefreshToken(hashToken(req.RefreshToken), models.SessionRevokedLogout); err != nil {
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.recordAuthEvent(r, "logout", actor, actor, nil)
+
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
 
-var jwtSecret = []byte(getJWTSecret())
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := h.db.RevokeUserSessions(user.ID, models.SessionRevokedLogoutAll)
+	if err != nil {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	h.recordAuthEvent(r, "logout_all", user, user, map[string]int64{"sessions_revoked": revoked})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "sessions_revoked": revoked})
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	refreshExpiresAt := now.Add(refreshTokenTTL)
+	session, err := h.db.RotateRefreshToken(hashToken(req.RefreshToken), hashToken(refreshToken), refreshExpiresAt, now)
+	if errors.Is(err, models.ErrRefreshTokenReused) {
+		h.recordAuthEvent(r, "session.refresh_reuse", nil, nil, nil)
+	}
+	if errors.Is(err, models.ErrRefreshTokenInvalid) || errors.Is(err, models.ErrRefreshTokenReused) {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.db.GetUserByID(session.UserID)
+	if err != nil || user == nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	roles, err := h.db.GetUserRoles(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := now.Add(accessTokenTTL).Unix()
+	token, err := generateToken(user, session.PublicID, roles, expiresAt)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoginResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt.Unix(),
+		User: User{
+			ID:            user.ID,
+			StudentID:     user.StudentID,
+			Name:          user.Name,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			Roles:         roles,
+			Permissions:   userPermissions(user, roles),
+		},
+	})
+}
+
+func (h *Handler) startSession(r *http.Request, user *models.User) (*LoginResponse, error) {
+	if IsAdmin(user) {
+		if _, err := h.db.GrantUserRole(user.ID, RoleAdmin); err != nil {
+			return nil, err
+		}
+	}
+
+	roles, err := h.db.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshExpiresAt := now.Add(refreshTokenTTL)
+	session, err := h.db.CreateSession(user.ID, r.UserAgent(), clientIP(r), hashToken(refreshToken), refreshExpiresAt)
+	if err != nil {
+		return nil, err
+	}
 
-func getJWTSecret() string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "flexibudget-default-secret-key"
+	expiresAt := now.Add(accessTokenTTL).Unix()
+	token, err := generateToken(user, session.PublicID, roles, expiresAt)
+	if err != nil {
+		return nil, err
 	}
-	return secret
+
+	return &LoginResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt.Unix(),
+		User: User{
+			ID:            user.ID,
+			StudentID:     user.StudentID,
+			Name:          user.Name,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			Roles:         roles,
+			Permissions:   userPermissions(user, roles),
+		},
+	}, nil
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func generateToken(userID int64, studentID string, expiresAt int64) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":    userID,
-		"student_id": studentID,
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	sessionTouchInterval = 5 * time.Minute
+)
+
+var errNoKeyring = errors.New("no token signing key is configured")
+
+func generateToken(user *models.User, sessionID string, roles []string, expiresAt int64) (string, error) {
+	if keyring == nil {
+		return "", errNoKeyring
+	}
+
+	return keyring.Sign(jwt.MapClaims{
+		"user_id":    user.ID,
+		"student_id": user.StudentID,
+		"sid":        sessionID,
+		"roles":      roles,
+		"perms":      userPermissions(user, roles),
+		"iat":        time.Now().Unix(),
 		"exp":        expiresAt,
 	})
+}
 
-	return token.SignedString(jwtSecret)
+type tokenClaims struct {
+	UserID    int64
+	StudentID string
+	SessionID string
+	Roles     []string
 }
 
-func ExtractUserID(r *http.Request) (int64, error) {
+func parseClaims(r *http.Request) (*tokenClaims, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return 0, fmt.Errorf("missing authorization header")
+		return nil, fmt.Errorf("missing authorization header")
 	}
 
 	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 	if tokenStr == authHeader {
-		return 0, fmt.Errorf("invalid token format")
+		return nil, fmt.Errorf("invalid token format")
 	}
 
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+	if keyring == nil {
+		return nil, errNoKeyring
+	}
 
+	token, err := keyring.Parse(tokenStr)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		userID, ok := claims["user_id"].(float64)
 		if !ok {
-			return 0, fmt.Errorf("invalid user_id in token")
+			return nil, fmt.Errorf("invalid user_id in token")
 		}
-		return int64(userID), nil
+		studentID, _ := claims["student_id"].(string)
+		sessionID, _ := claims["sid"].(string)
+		var roles []string
+		if list, ok := claims["roles"].([]interface{}); ok {
+			roles = []string{}
+			for _, v := range list {
+				if role, ok := v.(string); ok {
+					roles = append(roles, role)
+				}
+			}
+		}
+		return &tokenClaims{UserID: int64(userID), StudentID: studentID, SessionID: sessionID, Roles: roles}, nil
 	}
 
-	return 0, fmt.Errorf("invalid token")
+	return nil, fmt.Errorf("invalid token")
 }
 
-func AuthMiddleware(db *models.DB) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userID, err := ExtractUserID(r)
-			if err != nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+func authenticate(db *models.DB, r *http.Request) (*Principal, error) {
+	claims, err := parseClaims(r)
+	if err != nil {
+		return nil, err
+	}
 
-			user, err := db.GetUserByID(userID)
-			if err != nil || user == nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+	if claims.SessionID == "" {
+		return nil, fmt.Errorf("token has no session")
+	}
 
-			ctx := r.Context()
-			r = r.WithContext(ctx)
+	now := time.Now()
+	session, err := db.GetSessionByPublicID(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.UserID != claims.UserID || !session.Active(now) {
+		return nil, fmt.Errorf("session is revoked or expired")
+	}
+
+	user, err := db.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.LockedAt != nil {
+		return nil, fmt.Errorf("account is locked")
+	}
+
+	if now.Sub(session.LastUsedAt) > sessionTouchInterval {
+		if err := db.TouchSession(session.ID, now); err != nil {
+			return nil, err
+		}
+		session.LastUsedAt = now
+	}
 
-			next.ServeHTTP(w, r)
-		})
+	roles := claims.Roles
+	if roles == nil {
+		roles, err = db.GetUserRoles(user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Principal{
+		User:        user,
+		Session:     session,
+		Roles:       roles,
+		Permissions: userPermissions(user, roles),
+	}, nil
+}
+
+func IsAdmin(user *models.User) bool {
+	for _, id := range adminStudentIDs() {
+		if id == user.StudentID {
+			return true
+		}
+	}
+	return false
+}
+
+func adminStudentIDs() []string {
+	var ids []string
+	for _, id := range strings.Split(os.Getenv("ADMIN_STUDENT_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
 	}
+	return ids
 }
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	RoleStudent = "student"
+	RoleStaff   = "staff"
+	RoleAdmin   = "admin"
+)
+
+type Principal struct {
+	User        *models.User
+	Session     *models.Session
+	Roles       []string
+	Permissions []string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasPermission(permission string) bool {
+	for _, perm := range p.Permissions {
+		if perm == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	auditEventKey
+	requestIDKey
+)
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil && p.User != nil
+}
+
+func UserFrom(ctx context.Context) (*models.User, bool) {
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, false
+	}
+	return p.User, true
+}
+
+func UserIDFrom(ctx context.Context) (int64, bool) {
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return 0, false
+	}
+	return user.ID, true
+}
+
+func SessionFrom(ctx context.Context) (*models.Session, bool) {
+	p, ok := PrincipalFrom(ctx)
+	if !ok || p.Session == nil {
+		return nil, false
+	}
+	return p.Session, true
+}
+
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const minRSAKeyBits = 2048
+
+type signingMethodEdDSA struct{}
+
+var SigningMethodEdDSA jwt.SigningMethod = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(private, []byte(signingString))), nil
+}
+
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(public, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+type Keyring struct {
+	signingKey crypto.Signer
+	signing    *verificationKey
+	keys       map[string]*verificationKey
+	order      []string
+}
+
+var keyring *Keyring
+
+func SetKeyring(k *Keyring) {
+	keyring = k
+}
+
+func NewKeyring(signingKey crypto.Signer, verifyKeys ...crypto.PublicKey) (*Keyring, error) {
+	k := &Keyring{signingKey: signingKey, keys: map[string]*verificationKey{}}
+
+	signing, err := k.add(signingKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	k.signing = signing
+
+	for _, public := range verifyKeys {
+		if _, err := k.add(public); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func LoadKeyring(signingKeyFile string, verifyKeyFiles []string) (*Keyring, error) {
+	signingKey, err := readPrivateKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var verifyKeys []crypto.PublicKey
+	for _, file := range verifyKeyFiles {
+		public, err := readPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		verifyKeys = append(verifyKeys, public)
+	}
+	return NewKeyring(signingKey, verifyKeys...)
+}
+
+func (k *Keyring) add(public crypto.PublicKey) (*verificationKey, error) {
+	id, err := KeyID(public)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := k.keys[id]; ok {
+		return existing, nil
+	}
+
+	key := &verificationKey{id: id, public: public}
+	switch public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = SigningMethodEdDSA
+	}
+	k.keys[id] = key
+	k.order = append(k.order, id)
+	return key, nil
+}
+
+func (k *Keyring) SigningKeyID() string {
+	return k.signing.id
+}
+
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+	return token.SignedString(k.signingKey)
+}
+
+func (k *Keyring) Parse(tokenStr string) (*jwt.Token, error) {
+	return jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.public, nil
+	})
+}
+
+func (k *Keyring) JWKS() map[string]interface{} {
+	keys := make([]map[string]string, 0, len(k.order))
+	for _, id := range k.order {
+		key := k.keys[id]
+		jwk := publicJWK(key.public)
+		jwk["kid"] = key.id
+		jwk["alg"] = key.method.Alg()
+		jwk["use"] = "sig"
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+func (k *Keyring) ServeJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(k.JWKS())
+}
+
+func KeyID(public crypto.PublicKey) (string, error) {
+	var thumbprint string
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return "", fmt.Errorf("RSA signing keys must be at least %d bits", minRSAKeyBits)
+		}
+		jwk := publicJWK(key)
+		thumbprint = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk["e"], jwk["n"])
+	case ed25519.PublicKey:
+		thumbprint = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":%q}`, publicJWK(key)["x"])
+	default:
+		return "", fmt.Errorf("unsupported signing key type %T", public)
+	}
+	sum := sha256.Sum256([]byte(thumbprint))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func publicJWK(public crypto.PublicKey) map[string]string {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(key),
+		}
+	}
+	return map[string]string{}
+}
+
+func GenerateSigningKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case "EdDSA":
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	case "RS256":
+		return rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q (use EdDSA or RS256)", alg)
+}
+
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding signing key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM key", file)
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (crypto.Signer, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: expected a private key, found %s", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", file, err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported signing key type %T", file, key)
+}
+
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		return key, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		return key, nil
+	}
+
+	private, err := readPrivateKey(file)
+	if err != nil {
+		return nil, err
+	}
+	return private.Public(), nil
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	loginFailureWindow = 24 * time.Hour
+	loginLockBase      = time.Minute
+	loginLockMax       = time.Hour
+
+	accountLockThreshold = 5
+	ipLockThreshold      = 20
+)
+
+func AccountThrottleKey(studentID string) string {
+	return "account:" + studentID
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func loginThrottleKeys(r *http.Request, studentID string) []string {
+	return []string{AccountThrottleKey(studentID), ipThrottleKey(clientIP(r))}
+}
+
+func lockPolicy(key string) func(int) time.Duration {
+	threshold := accountLockThreshold
+	if strings.HasPrefix(key, "ip:") {
+		threshold = ipLockThreshold
+	}
+	return func(failures int) time.Duration {
+		if failures < threshold {
+			return 0
+		}
+		lock := loginLockBase
+		for i := threshold; i < failures && lock < loginLockMax; i++ {
+			lock *= 2
+		}
+		if lock > loginLockMax {
+			lock = loginLockMax
+		}
+		return lock
+	}
+}
+
+func dummyPasswordHash() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte("flexibudget-timing-equalizer"), bcrypt.DefaultCost)
+	if err != nil {
+		log.Fatalf("Failed to generate dummy password hash: %v", err)
+	}
+	return string(hash)
+}
+
+func (h *Handler) loginRetryAfter(keys []string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range keys {
+		t, err := h.db.GetLoginThrottle(key)
+		if err != nil {
+			return 0, err
+		}
+		if t.Locked(now) && t.LockedUntil.Sub(now) > wait {
+			wait = t.LockedUntil.Sub(now)
+		}
+	}
+	return wait, nil
+}
+
+func (h *Handler) loginFailed(r *http.Request, keys []string, target *models.User, studentID, reason string) {
+	h.recordAuthEvent(r, "login.failure", nil, target, map[string]string{"student_id": studentID, "reason": reason})
+
+	now := time.Now()
+	for _, key := range keys {
+		t, err := h.db.RecordLoginFailure(key, now, loginFailureWindow, lockPolicy(key))
+		if err != nil {
+			log.Printf("Failed to record login failure for %s: %v", key, err)
+			continue
+		}
+		if t.Locked(now) {
+			eventTarget := target
+			if !strings.HasPrefix(key, "account:") {
+				eventTarget = nil
+			}
+			h.recordAuthEvent(r, "login.lockout", nil, eventTarget, map[string]interface{}{
+				"key":          key,
+				"failures":     t.Failures,
+				"locked_until": t.LockedUntil,
+			})
+		}
+	}
+}
+
+func writeRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	http.Error(w, "Too many failed login attempts, please try again later", http.StatusTooManyRequests)
+}
+
+func (h *Handler) AdminLoginLockouts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		lockouts, err := h.db.ListLoginLockouts(time.Now())
+		if err != nil {
+			http.Error(w, "Failed to get lockouts", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lockouts)
+	case http.MethodPost:
+		var req struct {
+			Key    string `json:"key"`
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Key == "" {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			http.Error(w, "Reason is required", http.StatusBadRequest)
+			return
+		}
+
+		var target *models.User
+		if studentID := strings.TrimPrefix(req.Key, "account:"); studentID != req.Key {
+			user, err := h.db.GetUserByStudentID(studentID)
+			if err != nil {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			target = user
+		}
+
+		cleared, err := h.db.ClearLoginThrottle(req.Key)
+		if err != nil {
+			http.Error(w, "Failed to clear lockout", http.StatusInternalServerError)
+			return
+		}
+		if !cleared {
+			http.Error(w, "Lockout not found", http.StatusNotFound)
+			return
+		}
+
+		AnnotateAudit(r.Context(), "login.unlock", target, req.Reason, map[string]string{"key": req.Key})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "key": req.Key})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/mailer"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	passwordResetTTL      = time.Hour
+	passwordResetInterval = time.Minute
+	passwordResetSendWait = 30 * time.Second
+
+	mailWorkers   = 2
+	mailQueueSize = 100
+
+	minPasswordLength = 8
+)
+
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		StudentID string `json:"student_id"`
+		Email     string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	req.StudentID = strings.TrimSpace(req.StudentID)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.StudentID == "" && req.Email == "" {
+		http.Error(w, "Student ID or email is required", http.StatusBadRequest)
+		return
+	}
+
+	meta := AuditMeta(r)
+	h.queueMail(func() { h.sendPasswordReset(req.StudentID, req.Email, meta) })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If an account matches, a password reset link has been sent to its email address.",
+	})
+}
+
+func (h *Handler) queueMail(job func()) {
+	select {
+	case h.mailJobs <- job:
+	default:
+		log.Printf("Mail queue is full; dropping message")
+	}
+}
+
+func (h *Handler) runMailJobs() {
+	for job := range h.mailJobs {
+		job()
+	}
+}
+
+func (h *Handler) sendPasswordReset(studentID, email string, meta models.AuditMeta) {
+	var user *models.User
+	var err error
+	if studentID != "" {
+		user, err = h.db.GetUserByStudentID(studentID)
+	} else {
+		user, err = h.db.GetUserByEmail(email)
+	}
+	if err != nil {
+		log.Printf("Password reset lookup failed: %v", err)
+		return
+	}
+	if user == nil || (studentID != "" && email != "" && !strings.EqualFold(user.Email, email)) {
+		return
+	}
+
+	now := time.Now()
+	last, err := h.db.LastPasswordResetRequest(user.ID)
+	if err != nil {
+		log.Printf("Password reset lookup failed: %v", err)
+		return
+	}
+	if now.Sub(last) < passwordResetInterval {
+		return
+	}
+
+	token, err := generateRefreshToken()
+	if err != nil {
+		log.Printf("Password reset token generation failed: %v", err)
+		return
+	}
+
+	expiresAt := now.Add(passwordResetTTL)
+	if err := h.db.WithAudit(meta).CreatePasswordResetToken(user.ID, hashToken(token), meta.IPAddress, expiresAt); err != nil {
+		log.Printf("Password reset token creation failed: %v", err)
+		return
+	}
+
+	link := h.ResetURL + "?token=" + url.QueryEscape(token)
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendWait)
+	defer cancel()
+	err = h.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your FlexiBudget password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"We received a request to reset the password for student ID %s.\n"+
+			"Use the link below within the next hour to choose a new password:\n\n"+
+			"%s\n\n"+
+			"If you did not ask for this, you can ignore this email and your password will stay the same.\n",
+			user.Name, user.StudentID, link),
+	})
+	if err != nil {
+		log.Printf("Password reset email to user %d failed: %v", user.ID, err)
+	}
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, fmt.Sprintf("Password must be at least %d characters", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.db.WithAudit(AuditMeta(r)).ResetPassword(hashToken(req.Token), req.Password, time.Now())
+	if errors.Is(err, models.ErrResetTokenInvalid) {
+		http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.db.ClearLoginThrottle(AccountThrottleKey(user.StudentID)); err != nil {
+		log.Printf("Failed to clear login throttle for user %d: %v", user.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
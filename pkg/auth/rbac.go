@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	PermAccount         = "account:self"
+	PermAccountVerified = "account:verified"
+	PermDiningManage    = "dining:manage"
+	PermDepositsCredit  = "deposits:credit"
+	PermDepositsLoad    = "deposits:plan_load"
+	PermMealPlansManage = "meal_plans:manage"
+	PermRolesManage     = "roles:manage"
+	PermUsersManage     = "users:manage"
+	PermAuditRead       = "audit:read"
+)
+
+var rolePermissions = map[string][]string{
+	RoleStudent: {PermAccount, PermAccountVerified},
+	RoleStaff:   {PermAccount, PermAccountVerified, PermDiningManage, PermDepositsCredit},
+	RoleAdmin: {
+		PermAccount, PermAccountVerified, PermDiningManage, PermDepositsCredit, PermDepositsLoad,
+		PermMealPlansManage, PermRolesManage, PermUsersManage, PermAuditRead,
+	},
+}
+
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func PermissionsFor(roles []string) []string {
+	seen := map[string]bool{}
+	perms := []string{}
+	for _, role := range roles {
+		for _, perm := range rolePermissions[role] {
+			if !seen[perm] {
+				seen[perm] = true
+				perms = append(perms, perm)
+			}
+		}
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+func userPermissions(user *models.User, roles []string) []string {
+	perms := PermissionsFor(roles)
+	if user.EmailVerifiedAt != nil {
+		return perms
+	}
+	unverified := []string{}
+	for _, perm := range perms {
+		if perm != PermAccountVerified {
+			unverified = append(unverified, perm)
+		}
+	}
+	return unverified
+}
+
+type Policy map[string]string
+
+func (p Policy) Permission(route string) (string, error) {
+	perm, ok := p[route]
+	if !ok {
+		return "", fmt.Errorf("no permission policy for route %s", route)
+	}
+	return perm, nil
+}
+
+func RequirePermission(db *models.DB, permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticate(db, r)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !principal.HasPermission(permission) {
+				if permission == PermAccountVerified && principal.User.EmailVerifiedAt == nil {
+					http.Error(w, "Email address must be verified", http.StatusForbidden)
+					return
+				}
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func BootstrapAdmins(db *models.DB) error {
+	for _, id := range adminStudentIDs() {
+		user, err := db.GetUserByStudentID(id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			continue
+		}
+		if _, err := db.GrantUserRole(user.ID, RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
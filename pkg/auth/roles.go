@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type UserRoles struct {
+	StudentID   string   `json:"student_id"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+func (h *Handler) AdminUserRoles(w http.ResponseWriter, r *http.Request) {
+	principal, ok := PrincipalFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var studentID string
+	var roles, before []string
+	switch r.Method {
+	case http.MethodGet:
+		studentID = r.URL.Query().Get("student_id")
+	case http.MethodPut:
+		var req struct {
+			StudentID string   `json:"student_id"`
+			Roles     []string `json:"roles"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		studentID, roles = req.StudentID, req.Roles
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if studentID == "" {
+		http.Error(w, "Student ID is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.db.GetUserByStudentID(studentID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		if len(roles) == 0 {
+			http.Error(w, "At least one role is required", http.StatusBadRequest)
+			return
+		}
+		for _, role := range roles {
+			if !ValidRole(role) {
+				http.Error(w, "Unknown role: "+role, http.StatusBadRequest)
+				return
+			}
+		}
+		if !containsRole(roles, RoleAdmin) {
+			if user.ID == principal.User.ID {
+				http.Error(w, "You cannot remove your own admin role", http.StatusConflict)
+				return
+			}
+			if IsAdmin(user) {
+				http.Error(w, "Admin role is managed by ADMIN_STUDENT_IDS for this user", http.StatusConflict)
+				return
+			}
+		}
+		if before, err = h.db.GetUserRoles(user.ID); err != nil {
+			http.Error(w, "Failed to get roles", http.StatusInternalServerError)
+			return
+		}
+		if err := h.db.SetUserRoles(user.ID, roles); err != nil {
+			http.Error(w, "Failed to update roles", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	roles, err = h.db.GetUserRoles(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get roles", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		AnnotateAudit(r.Context(), "roles.update", user, "", map[string]interface{}{"before": before, "after": roles})
+	} else {
+		AnnotateAudit(r.Context(), "roles.view", user, "", nil)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserRoles{StudentID: user.StudentID, Roles: roles, Permissions: PermissionsFor(roles)})
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	oidcLoginTTL    = 10 * time.Minute
+	oidcStateCookie = "flexibudget_oidc_state"
+)
+
+type linkRequiresPasswordError struct {
+	token string
+}
+
+func (e *linkRequiresPasswordError) Error() string {
+	return "linking an existing account requires its password"
+}
+
+func (h *Handler) OIDCStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": h.OIDC != nil})
+}
+
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.OIDC == nil {
+		http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := generateRefreshToken()
+		if err != nil {
+			http.Error(w, "Failed to start single sign-on", http.StatusInternalServerError)
+			return
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	login := models.OIDCLogin{Nonce: nonce, CodeVerifier: verifier}
+	if err := h.db.CreateOIDCLogin(hashToken(state), login, time.Now().Add(oidcLoginTTL)); err != nil {
+		http.Error(w, "Failed to start single sign-on", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.OIDC.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.OIDC == nil {
+		http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc/", MaxAge: -1})
+
+	user, err := h.completeOIDCLogin(r)
+	var linkErr *linkRequiresPasswordError
+	if errors.As(err, &linkErr) {
+		h.recordAuthEvent(r, "login.failure", nil, user, map[string]string{"reason": "sso_link_requires_password", "method": "oidc"})
+		fragment := url.Values{"link_token": {linkErr.token}, "student_id": {user.StudentID}}
+		http.Redirect(w, r, "/login.html?error=sso_link_requires_password#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Single sign-on failed: %v", err)
+		h.recordAuthEvent(r, "login.failure", nil, user, map[string]string{"reason": "sso_failed", "method": "oidc"})
+		http.Redirect(w, r, "/login.html?error=sso_failed", http.StatusFound)
+		return
+	}
+
+	if user.LockedAt != nil {
+		h.recordAuthEvent(r, "login.failure", nil, user, map[string]string{"student_id": user.StudentID, "reason": "account_locked", "method": "oidc"})
+		http.Redirect(w, r, "/login.html?error=account_locked", http.StatusFound)
+		return
+	}
+
+	enrollment, err := h.enabledTOTP(user.ID)
+	if err != nil {
+		http.Redirect(w, r, "/login.html?error=sso_failed", http.StatusFound)
+		return
+	}
+	if enrollment != nil {
+		challenge, err := h.createChallenge(r, user)
+		if err != nil {
+			http.Redirect(w, r, "/login.html?error=sso_failed", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "/login.html#"+url.Values{"challenge_token": {challenge.ChallengeToken}}.Encode(), http.StatusFound)
+		return
+	}
+
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		http.Redirect(w, r, "/login.html?error=sso_failed", http.StatusFound)
+		return
+	}
+
+	h.recordAuthEvent(r, "login.success", user, user, map[string]string{"method": "oidc"})
+
+	fragment := url.Values{
+		"token":         {resp.Token},
+		"expires_at":    {strconv.FormatInt(resp.ExpiresAt, 10)},
+		"refresh_token": {resp.RefreshToken},
+		"user_id":       {strconv.FormatInt(resp.User.ID, 10)},
+		"name":          {resp.User.Name},
+		"student_id":    {resp.User.StudentID},
+	}
+	http.Redirect(w, r, "/sso.html#"+fragment.Encode(), http.StatusFound)
+}
+
+func (h *Handler) completeOIDCLogin(r *http.Request) (*models.User, error) {
+	q := r.URL.Query()
+	if idpError := q.Get("error"); idpError != "" {
+		return nil, fmt.Errorf("identity provider returned %s: %s", idpError, q.Get("error_description"))
+	}
+
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		return nil, fmt.Errorf("state does not match this browser")
+	}
+
+	login, err := h.db.ConsumeOIDCLogin(hashToken(state), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := h.OIDC.Exchange(r.Context(), q.Get("code"), login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.userForIdentity(r, identity)
+}
+
+func (h *Handler) userForIdentity(r *http.Request, identity *IdentityClaims) (*models.User, error) {
+	issuer := h.OIDC.Issuer()
+	user, err := h.db.GetUserByIdentity(issuer, identity.Subject)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	meta := AuditMeta(r)
+	user, err = h.db.GetUserByStudentID(identity.StudentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil && !(identity.EmailVerified && strings.EqualFold(identity.Email, user.Email)) {
+		token, err := generateRefreshToken()
+		if err != nil {
+			return nil, err
+		}
+		if err := h.db.CreatePendingIdentityLink(hashToken(token), user.ID, issuer, identity.Subject, time.Now().Add(oidcLoginTTL)); err != nil {
+			return nil, err
+		}
+		return user, &linkRequiresPasswordError{token: token}
+	}
+
+	if user == nil {
+		if identity.Email == "" {
+			return nil, fmt.Errorf("identity provider did not supply an email address for %s", identity.StudentID)
+		}
+		existing, err := h.db.GetUserByEmail(identity.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("email %s already belongs to student %s", identity.Email, existing.StudentID)
+		}
+
+		password, err := generateRefreshToken()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSpace(identity.Name)
+		if name == "" {
+			name = identity.StudentID
+		}
+		user, err = h.db.CreateUser(identity.StudentID, name, identity.Email, password)
+		if err != nil {
+			return nil, err
+		}
+		h.recordAuthEvent(r, "register", user, user, map[string]string{"source": "oidc"})
+	}
+
+	if err := h.db.WithAudit(meta).LinkIdentity(user.ID, issuer, identity.Subject); err != nil {
+		return nil, err
+	}
+
+	if identity.EmailVerified && user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := h.db.MarkEmailVerified(user.ID, now); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	return user, nil
+}
@@ -0,0 +1,395 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pyne/flexibudget/pkg/fakeidp"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const testClientID = "flexibudget-test"
+
+type ssoFixture struct {
+	db     *models.DB
+	h      *Handler
+	idp    *fakeidp.Server
+	app    *httptest.Server
+	oidc   *OIDCProvider
+	client *http.Client
+}
+
+func newTestDB(t *testing.T) *models.DB {
+	t.Helper()
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "auth.db"))
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestKeyring(t *testing.T) {
+	t.Helper()
+
+	key, err := GenerateSigningKey("EdDSA")
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	k, err := NewKeyring(key)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	SetKeyring(k)
+	t.Cleanup(func() { SetKeyring(nil) })
+}
+
+func noRedirects(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+func newSSOFixture(t *testing.T) *ssoFixture {
+	t.Helper()
+
+	db := newTestDB(t)
+	newTestKeyring(t)
+
+	idp, idpServer, err := fakeidp.Start(testClientID)
+	if err != nil {
+		t.Fatalf("fakeidp.Start: %v", err)
+	}
+	t.Cleanup(idpServer.Close)
+
+	h := NewHandler(db, nil)
+	router := http.NewServeMux()
+	router.HandleFunc("/api/auth/oidc/login", h.OIDCLogin)
+	router.HandleFunc("/api/auth/oidc/callback", h.OIDCCallback)
+	app := httptest.NewServer(router)
+	t.Cleanup(app.Close)
+
+	h.OIDC, err = NewOIDCProvider(context.Background(), OIDCConfig{
+		Issuer:         idp.Issuer,
+		ClientID:       testClientID,
+		RedirectURL:    app.URL + "/api/auth/oidc/callback",
+		StudentIDClaim: "student_id",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ssoFixture{
+		db:     db,
+		h:      h,
+		idp:    idp,
+		app:    app,
+		oidc:   h.OIDC,
+		client: &http.Client{Jar: jar, CheckRedirect: noRedirects},
+	}
+}
+
+func (f *ssoFixture) location(t *testing.T, client *http.Client, target string) *url.URL {
+	t.Helper()
+
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("GET %s: status %d, want %d", target, resp.StatusCode, http.StatusFound)
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	return loc
+}
+
+func withLoginHint(u *url.URL, subject string) string {
+	q := u.Query()
+	q.Set("login_hint", subject)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (f *ssoFixture) startLogin(t *testing.T, subject string) *url.URL {
+	t.Helper()
+
+	authorize := f.location(t, f.client, f.app.URL+"/api/auth/oidc/login")
+	return f.location(t, f.client, withLoginHint(authorize, subject))
+}
+
+func (f *ssoFixture) signIn(t *testing.T, subject string) *url.URL {
+	t.Helper()
+
+	callback := f.startLogin(t, subject)
+	return f.location(t, f.client, callback.String())
+}
+
+func requireSignedIn(t *testing.T, landing *url.URL) url.Values {
+	t.Helper()
+
+	if landing.Path != "/sso.html" {
+		t.Fatalf("landed on %s, want /sso.html", landing)
+	}
+	fragment, err := url.ParseQuery(landing.Fragment)
+	if err != nil || fragment.Get("token") == "" {
+		t.Fatalf("landing fragment has no token: %q", landing.Fragment)
+	}
+	return fragment
+}
+
+func requireSSOFailed(t *testing.T, landing *url.URL) {
+	t.Helper()
+
+	if landing.Path != "/login.html" || landing.Query().Get("error") != "sso_failed" {
+		t.Fatalf("landed on %s, want /login.html?error=sso_failed", landing)
+	}
+}
+
+func requireLinkRequiresPassword(t *testing.T, landing *url.URL) string {
+	t.Helper()
+
+	if landing.Path != "/login.html" || landing.Query().Get("error") != "sso_link_requires_password" {
+		t.Fatalf("landed on %s, want /login.html?error=sso_link_requires_password", landing)
+	}
+	fragment, err := url.ParseQuery(landing.Fragment)
+	if err != nil || fragment.Get("link_token") == "" {
+		t.Fatalf("landing fragment has no link token: %q", landing.Fragment)
+	}
+	return fragment.Get("link_token")
+}
+
+func (f *ssoFixture) passwordLogin(t *testing.T, studentID, password, linkToken string) int {
+	t.Helper()
+
+	body, err := json.Marshal(LoginRequest{StudentID: studentID, Password: password, LinkToken: linkToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	f.h.Login(rec, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+	return rec.Code
+}
+
+func (f *ssoFixture) authCode(t *testing.T, subject, nonce, verifier string) string {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: noRedirects}
+	authorize, err := url.Parse(f.oidc.AuthCodeURL("state", nonce, verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := f.location(t, client, withLoginHint(authorize, subject))
+	code := callback.Query().Get("code")
+	if code == "" {
+		t.Fatalf("authorize redirect has no code: %s", callback)
+	}
+	return code
+}
+
+func TestOIDCProvisionsNewAccount(t *testing.T) {
+	f := newSSOFixture(t)
+	f.idp.AddUser(fakeidp.User{Subject: "sub-1", StudentID: "30000001", Name: "Jit User", Email: "jit@example.edu", EmailVerified: true})
+
+	fragment := requireSignedIn(t, f.signIn(t, "sub-1"))
+	if got := fragment.Get("student_id"); got != "30000001" {
+		t.Fatalf("signed in as %q, want 30000001", got)
+	}
+
+	user, err := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-1")
+	if err != nil || user == nil {
+		t.Fatalf("identity was not linked: %v", err)
+	}
+	if user.StudentID != "30000001" || user.Name != "Jit User" || user.Email != "jit@example.edu" {
+		t.Fatalf("provisioned user = %+v", user)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("provisioned user email should be verified")
+	}
+
+	requireSignedIn(t, f.signIn(t, "sub-1"))
+	again, err := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-1")
+	if err != nil || again == nil || again.ID != user.ID {
+		t.Fatalf("second sign-in resolved to %+v, want user %d", again, user.ID)
+	}
+}
+
+func TestOIDCLinksExistingAccountAndVerifiesEmail(t *testing.T) {
+	f := newSSOFixture(t)
+	existing, err := f.db.CreateUser("30000002", "Existing User", "existing@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	f.idp.AddUser(fakeidp.User{Subject: "sub-2", StudentID: "30000002", Name: "Existing User", Email: "Existing@example.edu", EmailVerified: true})
+
+	requireSignedIn(t, f.signIn(t, "sub-2"))
+
+	user, err := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-2")
+	if err != nil || user == nil {
+		t.Fatalf("identity was not linked: %v", err)
+	}
+	if user.ID != existing.ID {
+		t.Fatalf("linked user %d, want existing user %d", user.ID, existing.ID)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("verified email from the identity provider should mark the account verified")
+	}
+}
+
+func TestOIDCLinkRequiresPasswordForUnverifiedEmail(t *testing.T) {
+	f := newSSOFixture(t)
+	existing, err := f.db.CreateUser("30000003", "Unverified User", "unverified@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	f.idp.AddUser(fakeidp.User{Subject: "sub-3", StudentID: "30000003", Name: "Unverified User", Email: "unverified@example.edu"})
+
+	token := requireLinkRequiresPassword(t, f.signIn(t, "sub-3"))
+	if user, _ := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-3"); user != nil {
+		t.Fatal("identity was linked without confirming the password")
+	}
+
+	if code := f.passwordLogin(t, "30000003", "wrong-password", token); code != http.StatusUnauthorized {
+		t.Fatalf("login with wrong password: status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if user, _ := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-3"); user != nil {
+		t.Fatal("identity was linked after a failed password login")
+	}
+
+	if code := f.passwordLogin(t, "30000003", "password123", token); code != http.StatusOK {
+		t.Fatalf("login with link token: status %d, want %d", code, http.StatusOK)
+	}
+	if code := f.passwordLogin(t, "30000003", "password123", token); code != http.StatusBadRequest {
+		t.Fatalf("reusing link token: status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	requireSignedIn(t, f.signIn(t, "sub-3"))
+	user, err := f.db.GetUserByID(existing.ID)
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if user.EmailVerifiedAt != nil {
+		t.Fatal("unverified email from the identity provider should not mark the account verified")
+	}
+}
+
+func TestOIDCRefusesToLinkAccountWithDifferentEmail(t *testing.T) {
+	f := newSSOFixture(t)
+	if _, err := f.db.CreateUser("30000010", "Victim User", "victim@example.edu", "password123"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	f.idp.AddUser(fakeidp.User{Subject: "sub-10", StudentID: "30000010", Name: "Attacker", Email: "attacker@example.edu", EmailVerified: true})
+
+	token := requireLinkRequiresPassword(t, f.signIn(t, "sub-10"))
+	if user, _ := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-10"); user != nil {
+		t.Fatalf("identity was linked to %s", user.StudentID)
+	}
+
+	if _, err := f.db.CreateUser("30000011", "Other User", "other@example.edu", "password456"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if code := f.passwordLogin(t, "30000011", "password456", token); code != http.StatusBadRequest {
+		t.Fatalf("redeeming link token as another account: status %d, want %d", code, http.StatusBadRequest)
+	}
+	if user, _ := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-10"); user != nil {
+		t.Fatalf("identity was linked to %s", user.StudentID)
+	}
+}
+
+func TestOIDCRejectsEmailOwnedByAnotherStudent(t *testing.T) {
+	f := newSSOFixture(t)
+	if _, err := f.db.CreateUser("30000004", "Email Owner", "taken@example.edu", "password123"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	f.idp.AddUser(fakeidp.User{Subject: "sub-4", StudentID: "30000005", Name: "Someone Else", Email: "taken@example.edu", EmailVerified: true})
+
+	requireSSOFailed(t, f.signIn(t, "sub-4"))
+
+	user, err := f.db.GetUserByIdentity(f.oidc.Issuer(), "sub-4")
+	if err != nil {
+		t.Fatalf("GetUserByIdentity: %v", err)
+	}
+	if user != nil {
+		t.Fatalf("identity was linked to %s", user.StudentID)
+	}
+	if created, _ := f.db.GetUserByStudentID("30000005"); created != nil {
+		t.Fatal("account was provisioned for a conflicting email")
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	f := newSSOFixture(t)
+	f.idp.AddUser(fakeidp.User{Subject: "sub-6", StudentID: "30000006", Name: "State User", Email: "state@example.edu", EmailVerified: true})
+
+	callback := f.startLogin(t, "sub-6")
+	q := callback.Query()
+	q.Set("state", "forged-state")
+	callback.RawQuery = q.Encode()
+	requireSSOFailed(t, f.location(t, f.client, callback.String()))
+
+	callback = f.startLogin(t, "sub-6")
+	otherBrowser := &http.Client{CheckRedirect: noRedirects}
+	requireSSOFailed(t, f.location(t, otherBrowser, callback.String()))
+
+	if user, _ := f.db.GetUserByStudentID("30000006"); user != nil {
+		t.Fatal("account was provisioned without a matching state")
+	}
+}
+
+func TestOIDCCallbackStateIsSingleUse(t *testing.T) {
+	f := newSSOFixture(t)
+	f.idp.AddUser(fakeidp.User{Subject: "sub-7", StudentID: "30000007", Name: "Replay User", Email: "replay@example.edu", EmailVerified: true})
+
+	callback := f.startLogin(t, "sub-7")
+	state := callback.Query().Get("state")
+	requireSignedIn(t, f.location(t, f.client, callback.String()))
+
+	f.client.Jar.SetCookies(callback, []*http.Cookie{{Name: oidcStateCookie, Value: state, Path: "/api/auth/oidc/"}})
+	requireSSOFailed(t, f.location(t, f.client, callback.String()))
+}
+
+func TestOIDCExchangeRejectsPKCEVerifierMismatch(t *testing.T) {
+	f := newSSOFixture(t)
+	f.idp.AddUser(fakeidp.User{Subject: "sub-8", StudentID: "30000008", Email: "pkce@example.edu"})
+
+	code := f.authCode(t, "sub-8", "nonce", "right-verifier")
+	_, err := f.oidc.Exchange(context.Background(), code, "wrong-verifier", "nonce")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("Exchange with wrong verifier: err = %v, want invalid_grant", err)
+	}
+
+	code = f.authCode(t, "sub-8", "nonce", "right-verifier")
+	identity, err := f.oidc.Exchange(context.Background(), code, "right-verifier", "nonce")
+	if err != nil {
+		t.Fatalf("Exchange with matching verifier: %v", err)
+	}
+	if identity.Subject != "sub-8" || identity.StudentID != "30000008" {
+		t.Fatalf("identity = %+v", identity)
+	}
+}
+
+func TestOIDCExchangeRejectsNonceMismatch(t *testing.T) {
+	f := newSSOFixture(t)
+	f.idp.AddUser(fakeidp.User{Subject: "sub-9", StudentID: "30000009", Email: "nonce@example.edu"})
+
+	code := f.authCode(t, "sub-9", "issued-nonce", "verifier")
+	_, err := f.oidc.Exchange(context.Background(), code, "verifier", "expected-nonce")
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("Exchange with mismatched nonce: err = %v, want nonce error", err)
+	}
+}
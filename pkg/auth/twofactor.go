@@ -0,0 +1,407 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+	"github.com/pyne/flexibudget/pkg/totp"
+)
+
+const (
+	totpIssuer        = "FlexiBudget"
+	loginChallengeTTL = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type ChallengeResponse struct {
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresAt      int64  `json:"expires_at"`
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func (h *Handler) checkSecondFactor(user *models.User, enrollment *models.TOTPEnrollment, code string) (string, bool, error) {
+	if step, ok := h.TOTP.Validate(enrollment.Secret, code, enrollment.LastUsedStep); ok {
+		used, err := h.db.UseTOTPStep(user.ID, step)
+		return "totp", used, err
+	}
+	used, err := h.db.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	return "recovery_code", used, err
+}
+
+func (h *Handler) enabledTOTP(userID int64) (*models.TOTPEnrollment, error) {
+	enrollment, err := h.db.GetTOTP(userID)
+	if err != nil || enrollment == nil || enrollment.EnabledAt == nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+func (h *Handler) createChallenge(r *http.Request, user *models.User) (*ChallengeResponse, error) {
+	token, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(loginChallengeTTL)
+	if err := h.db.CreateLoginChallenge(user.ID, hashToken(token), clientIP(r), expiresAt); err != nil {
+		return nil, err
+	}
+
+	h.recordAuthEvent(r, "login.challenge", nil, user, nil)
+
+	return &ChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresAt:      expiresAt.Unix(),
+	}, nil
+}
+
+func (h *Handler) VerifyLoginChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	challengeHash := hashToken(req.ChallengeToken)
+	userID, err := h.db.GetLoginChallenge(challengeHash, time.Now())
+	if errors.Is(err, models.ErrChallengeInvalid) {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.db.GetUserByID(userID)
+	if err != nil || user == nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user.LockedAt != nil {
+		http.Error(w, "Account is locked", http.StatusForbidden)
+		return
+	}
+
+	keys := loginThrottleKeys(r, user.StudentID)
+	wait, err := h.loginRetryAfter(keys, time.Now())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeRetryAfter(w, wait)
+		return
+	}
+
+	enrollment, err := h.enabledTOTP(user.ID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if enrollment == nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	method, ok, err := h.checkSecondFactor(user, enrollment, req.Code)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		h.loginFailed(r, keys, user, user.StudentID, "bad_second_factor")
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.db.CompleteLoginChallenge(challengeHash, time.Now()); err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := h.db.ClearLoginThrottle(AccountThrottleKey(user.StudentID)); err != nil {
+		log.Printf("Failed to clear login throttle for user %d: %v", user.ID, err)
+	}
+
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	h.recordAuthEvent(r, "login.success", user, user, map[string]string{"second_factor": method})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) TwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.db.GetTOTP(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get two-factor status", http.StatusInternalServerError)
+		return
+	}
+	remaining, err := h.db.RemainingRecoveryCodes(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get two-factor status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":                  enrollment != nil && enrollment.EnabledAt != nil,
+		"pending":                  enrollment != nil && enrollment.EnabledAt == nil,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+func (h *Handler) TwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.db.StartTOTPEnrollment(user.ID, secret)
+	if errors.Is(err, models.ErrTOTPAlreadyEnabled) {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      secret,
+		"otpauth_uri": h.TOTP.URI(totpIssuer, user.StudentID, secret),
+	})
+}
+
+func (h *Handler) TwoFactorEnable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.db.GetTOTP(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if enrollment == nil {
+		http.Error(w, "Start two-factor setup first", http.StatusConflict)
+		return
+	}
+	if enrollment.EnabledAt != nil {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	step, ok := h.TOTP.Validate(enrollment.Secret, req.Code, 0)
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.db.WithAudit(AuditMeta(r)).EnableTOTP(user.ID, step, hashes, time.Now())
+	if errors.Is(err, models.ErrTOTPNotPending) {
+		http.Error(w, "Start two-factor setup first", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"enabled": true, "recovery_codes": codes})
+}
+
+func (h *Handler) TwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, enrollment, ok := h.reauthenticate(w, r)
+	if !ok {
+		return
+	}
+	if enrollment == nil {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.db.WithAudit(AuditMeta(r)).RegenerateRecoveryCodes(user.ID, hashes, time.Now()); err != nil {
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": codes})
+}
+
+func (h *Handler) TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, enrollment, ok := h.reauthenticate(w, r)
+	if !ok {
+		return
+	}
+	if enrollment == nil {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+
+	if err := h.db.WithAudit(AuditMeta(r)).DisableTOTP(user.ID); err != nil {
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": false})
+}
+
+func (h *Handler) reauthenticate(w http.ResponseWriter, r *http.Request) (*models.User, *models.TOTPEnrollment, bool) {
+	user, ok := UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	keys := loginThrottleKeys(r, user.StudentID)
+	wait, err := h.loginRetryAfter(keys, time.Now())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if wait > 0 {
+		writeRetryAfter(w, wait)
+		return nil, nil, false
+	}
+
+	enrollment, err := h.enabledTOTP(user.ID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	verified := h.db.VerifyPassword(user, req.Password)
+	if verified && enrollment != nil {
+		verified, err = false, nil
+		if req.Code != "" {
+			_, verified, err = h.checkSecondFactor(user, enrollment, req.Code)
+		}
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return nil, nil, false
+		}
+	}
+	if !verified {
+		h.loginFailed(r, keys, user, user.StudentID, "reauth_failed")
+		http.Error(w, "Invalid password or code", http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	return user, enrollment, true
+}
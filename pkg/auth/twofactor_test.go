@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+	"github.com/pyne/flexibudget/pkg/totp"
+)
+
+func enrollTOTP(t *testing.T, h *Handler, user *models.User, at time.Time) (string, []string) {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	if err := h.db.StartTOTPEnrollment(user.ID, secret); err != nil {
+		t.Fatalf("StartTOTPEnrollment: %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes: %v", err)
+	}
+	if err := h.db.EnableTOTP(user.ID, 0, hashes, at); err != nil {
+		t.Fatalf("EnableTOTP: %v", err)
+	}
+	return secret, codes
+}
+
+func newTwoFactorUser(t *testing.T, now time.Time) (*Handler, *models.User) {
+	t.Helper()
+
+	db := newTestDB(t)
+	h := NewHandler(db, nil)
+	h.TOTP.Now = func() time.Time { return now }
+
+	user, err := db.CreateUser("40000001", "Two Factor", "2fa@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return h, user
+}
+
+func checkCode(t *testing.T, h *Handler, user *models.User, code string) (string, bool) {
+	t.Helper()
+
+	enrollment, err := h.enabledTOTP(user.ID)
+	if err != nil || enrollment == nil {
+		t.Fatalf("enabledTOTP: %v", err)
+	}
+	method, ok, err := h.checkSecondFactor(user, enrollment, code)
+	if err != nil {
+		t.Fatalf("checkSecondFactor: %v", err)
+	}
+	return method, ok
+}
+
+func TestSecondFactorRejectsReplayedCode(t *testing.T) {
+	now := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	h, user := newTwoFactorUser(t, now)
+	secret, _ := enrollTOTP(t, h, user, now)
+
+	code, err := h.TOTP.Code(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method, ok := checkCode(t, h, user, code); !ok || method != "totp" {
+		t.Fatalf("first use: method = %s, ok = %v", method, ok)
+	}
+	if _, ok := checkCode(t, h, user, code); ok {
+		t.Fatal("replayed code accepted")
+	}
+
+	h.TOTP.Now = func() time.Time { return now.Add(h.TOTP.Period) }
+	if _, ok := checkCode(t, h, user, code); ok {
+		t.Fatal("replayed code accepted inside the skew window")
+	}
+	next, _ := h.TOTP.Code(secret)
+	if _, ok := checkCode(t, h, user, next); !ok {
+		t.Fatal("next code rejected")
+	}
+
+	previous, _ := h.TOTP.CodeAt(secret, h.TOTP.Step(now)-1)
+	if _, ok := checkCode(t, h, user, previous); ok {
+		t.Fatal("code older than the last used step accepted")
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	now := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	h, user := newTwoFactorUser(t, now)
+	_, codes := enrollTOTP(t, h, user, now)
+
+	remaining, err := h.db.RemainingRecoveryCodes(user.ID)
+	if err != nil || remaining != recoveryCodeCount {
+		t.Fatalf("RemainingRecoveryCodes = %d, %v; want %d", remaining, err, recoveryCodeCount)
+	}
+
+	if method, ok := checkCode(t, h, user, strings.ToUpper(codes[0])); !ok || method != "recovery_code" {
+		t.Fatalf("first use: method = %s, ok = %v", method, ok)
+	}
+	if _, ok := checkCode(t, h, user, codes[0]); ok {
+		t.Fatal("recovery code accepted twice")
+	}
+	if _, ok := checkCode(t, h, user, strings.ReplaceAll(codes[1], "-", "")); !ok {
+		t.Fatal("unused recovery code rejected")
+	}
+
+	remaining, err = h.db.RemainingRecoveryCodes(user.ID)
+	if err != nil || remaining != recoveryCodeCount-2 {
+		t.Fatalf("RemainingRecoveryCodes = %d, %v; want %d", remaining, err, recoveryCodeCount-2)
+	}
+
+	_, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.db.RegenerateRecoveryCodes(user.ID, hashes, now); err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if _, ok := checkCode(t, h, user, codes[2]); ok {
+		t.Fatal("recovery code accepted after regeneration")
+	}
+}
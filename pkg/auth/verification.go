@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/mailer"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	emailVerificationTTL      = 48 * time.Hour
+	emailVerificationInterval = time.Minute
+	emailVerificationWindow   = 24 * time.Hour
+	emailVerificationMaxSends = 5
+)
+
+func (h *Handler) validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("Invalid email address")
+	}
+	if len(h.EmailDomains) == 0 {
+		return nil
+	}
+
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+	for _, allowed := range h.EmailDomains {
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Email must be a campus address (%s)", strings.Join(h.EmailDomains, ", "))
+}
+
+func (h *Handler) sendEmailVerification(user *models.User, meta models.AuditMeta) error {
+	token, err := generateRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(emailVerificationTTL)
+	if err := h.db.WithAudit(meta).CreateEmailVerificationToken(user.ID, user.Email, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	link := h.VerifyURL + "?token=" + url.QueryEscape(token)
+	h.queueMail(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendWait)
+		defer cancel()
+		err := h.mailer.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: "Verify your FlexiBudget email address",
+			Body: fmt.Sprintf("Hi %s,\n\n"+
+				"Please confirm that %s is your email address by opening the link below within the next 48 hours:\n\n"+
+				"%s\n\n"+
+				"Until your address is verified you can view your account, but deposits, dining orders and fairy requests stay disabled.\n",
+				user.Name, user.Email, link),
+		})
+		if err != nil {
+			log.Printf("Verification email to user %d failed: %v", user.ID, err)
+		}
+	})
+	return nil
+}
+
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.db.WithAudit(AuditMeta(r)).VerifyEmail(hashToken(req.Token), time.Now())
+	if errors.Is(err, models.ErrVerificationTokenInvalid) {
+		http.Error(w, "Invalid or expired verification link", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		http.Error(w, "Email address is already verified", http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	count, last, err := h.db.EmailVerificationRequests(user.ID, now.Add(-emailVerificationWindow))
+	if err != nil {
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+	if wait := last.Add(emailVerificationInterval).Sub(now); wait > 0 || count >= emailVerificationMaxSends {
+		if wait <= 0 {
+			wait = emailVerificationWindow
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		http.Error(w, "Too many verification emails, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	if err := h.sendEmailVerification(user, AuditMeta(r)); err != nil {
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
@@ -0,0 +1,193 @@
+package closeout
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+var ErrTermNotEnded = errors.New("term has not ended yet")
+
+type Options struct {
+	DryRun bool
+	Force  bool
+	Now    time.Time
+}
+
+type Account struct {
+	UserID          int64   `json:"user_id"`
+	StudentID       string  `json:"student_id"`
+	Plan            string  `json:"plan"`
+	Rule            string  `json:"rule"`
+	BalanceBefore   float64 `json:"balance_before"`
+	SwipesRemaining int     `json:"swipes_remaining"`
+	Converted       float64 `json:"converted"`
+	Forfeited       float64 `json:"forfeited"`
+	RolledOver      float64 `json:"rolled_over"`
+	BalanceAfter    float64 `json:"balance_after"`
+}
+
+type Report struct {
+	Term            models.Term `json:"term"`
+	DryRun          bool        `json:"dry_run"`
+	Accounts        []Account   `json:"accounts"`
+	TotalForfeited  float64     `json:"total_forfeited"`
+	TotalRolledOver float64     `json:"total_rolled_over"`
+	TotalConverted  float64     `json:"total_converted"`
+	SwipesForfeited int         `json:"swipes_forfeited"`
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func apply(plan *models.MealPlan, balance float64, swipes int) (converted, forfeited, rolledOver float64) {
+	if plan.CloseoutRule == models.CloseoutConvert && swipes > 0 {
+		converted = roundCents(float64(swipes) * plan.ConversionRate)
+	}
+
+	available := balance + converted
+	if available <= 0 {
+		return converted, 0, 0
+	}
+
+	switch plan.CloseoutRule {
+	case models.CloseoutRollover, models.CloseoutConvert:
+		rolledOver = available
+		if plan.RolloverCap > 0 && rolledOver > plan.RolloverCap {
+			rolledOver = plan.RolloverCap
+		}
+	default:
+		rolledOver = 0
+	}
+
+	forfeited = roundCents(available - rolledOver)
+	return converted, forfeited, rolledOver
+}
+
+func Run(db *models.DB, termName string, opts Options) (*Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	term, err := db.GetTermByName(termName)
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, fmt.Errorf("term %q not found", termName)
+	}
+	if term.ClosedAt != nil {
+		return nil, models.ErrTermClosed
+	}
+	if opts.Now.Before(term.EndsAt) && !opts.Force && !opts.DryRun {
+		return nil, ErrTermNotEnded
+	}
+
+	users, err := db.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	defaultPlan, err := db.GetDefaultMealPlan(term.Kind)
+	if err != nil && !errors.Is(err, models.ErrNoDefaultMealPlan) {
+		return nil, err
+	}
+
+	plans := map[int64]*models.MealPlan{}
+	var userIDs []int64
+	studentIDs := map[int64]string{}
+	for _, user := range users {
+		plan := defaultPlan
+		assignment, err := db.GetUserMealPlan(user.ID, term.ID)
+		if err != nil {
+			return nil, err
+		}
+		if assignment != nil {
+			plan = &assignment.MealPlan
+		}
+		if plan == nil {
+			continue
+		}
+		plans[user.ID] = plan
+		studentIDs[user.ID] = user.StudentID
+		userIDs = append(userIDs, user.ID)
+	}
+
+	report := &Report{Term: *term, DryRun: opts.DryRun, Accounts: []Account{}}
+	settle := func(userID int64, balance float64, swipes int) []models.BalanceAdjustment {
+		plan := plans[userID]
+		converted, forfeited, rolledOver := apply(plan, balance, swipes)
+		report.Accounts = append(report.Accounts, Account{
+			UserID:          userID,
+			StudentID:       studentIDs[userID],
+			Plan:            plan.Name,
+			Rule:            plan.CloseoutRule,
+			BalanceBefore:   balance,
+			SwipesRemaining: swipes,
+			Converted:       converted,
+			Forfeited:       forfeited,
+			RolledOver:      rolledOver,
+			BalanceAfter:    roundCents(balance + converted - forfeited),
+		})
+		report.TotalConverted += converted
+		report.TotalForfeited += forfeited
+		report.TotalRolledOver += rolledOver
+		report.SwipesForfeited += swipes
+
+		var adjustments []models.BalanceAdjustment
+		termID := term.ID
+		if converted > 0 {
+			adjustments = append(adjustments, models.BalanceAdjustment{
+				UserID:    userID,
+				Kind:      models.AdjustmentCloseoutConversion,
+				Amount:    converted,
+				Reason:    fmt.Sprintf("Converted %d unused meal swipes to dining dollars at close of %s", swipes, term.Name),
+				TermID:    &termID,
+				CreatedAt: opts.Now,
+			})
+		}
+		if forfeited > 0 {
+			adjustments = append(adjustments, models.BalanceAdjustment{
+				UserID:    userID,
+				Kind:      models.AdjustmentCloseoutForfeit,
+				Amount:    -forfeited,
+				Reason:    fmt.Sprintf("Forfeited unused dining dollars at close of %s (%s plan, %s rule)", term.Name, plan.Name, plan.CloseoutRule),
+				TermID:    &termID,
+				CreatedAt: opts.Now,
+			})
+		}
+		return adjustments
+	}
+
+	if opts.DryRun {
+		for _, userID := range userIDs {
+			balance, swipes, err := db.GetCloseoutBalance(userID, term)
+			if err != nil {
+				return nil, err
+			}
+			settle(userID, balance, swipes)
+		}
+	} else if err := db.CloseTerm(term.ID, userIDs, opts.Now, settle); err != nil {
+		return nil, err
+	}
+
+	report.TotalConverted = roundCents(report.TotalConverted)
+	report.TotalForfeited = roundCents(report.TotalForfeited)
+	report.TotalRolledOver = roundCents(report.TotalRolledOver)
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	closed, err := db.GetTermByID(term.ID)
+	if err != nil {
+		return nil, err
+	}
+	report.Term = *closed
+
+	return report, nil
+}
@@ -0,0 +1,215 @@
+package closeout
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func TestApply(t *testing.T) {
+	rollover := &models.MealPlan{CloseoutRule: models.CloseoutRollover, RolloverCap: 300}
+	uncapped := &models.MealPlan{CloseoutRule: models.CloseoutRollover}
+	forfeit := &models.MealPlan{CloseoutRule: models.CloseoutForfeit}
+	convert := &models.MealPlan{CloseoutRule: models.CloseoutConvert, RolloverCap: 200, ConversionRate: 4}
+
+	tests := []struct {
+		name       string
+		plan       *models.MealPlan
+		balance    float64
+		swipes     int
+		converted  float64
+		forfeited  float64
+		rolledOver float64
+	}{
+		{"rollover under cap", rollover, 125.50, 0, 0, 0, 125.50},
+		{"rollover over cap", rollover, 512.37, 0, 0, 212.37, 300},
+		{"rollover uncapped", uncapped, 812.01, 0, 0, 0, 812.01},
+		{"forfeit", forfeit, 99.99, 0, 0, 99.99, 0},
+		{"convert swipes", convert, 50, 10, 40, 0, 90},
+		{"convert over cap", convert, 180.25, 7, 28, 8.25, 200},
+		{"zero balance", rollover, 0, 0, 0, 0, 0},
+		{"negative balance", rollover, -12.40, 0, 0, 0, 0},
+		{"negative balance after conversion", convert, -50, 5, 20, 0, 0},
+	}
+
+	for _, tt := range tests {
+		converted, forfeited, rolledOver := apply(tt.plan, tt.balance, tt.swipes)
+		if converted != tt.converted || forfeited != tt.forfeited || rolledOver != tt.rolledOver {
+			t.Errorf("%s: apply = (%.2f, %.2f, %.2f), want (%.2f, %.2f, %.2f)", tt.name,
+				converted, forfeited, rolledOver, tt.converted, tt.forfeited, tt.rolledOver)
+		}
+	}
+}
+
+type fixture struct {
+	db     *models.DB
+	term   *models.Term
+	endsAt time.Time
+	now    time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "closeout.db"))
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	now := time.Now()
+	term, err := db.EnsureTerm(now)
+	if err != nil {
+		t.Fatalf("EnsureTerm: %v", err)
+	}
+	return &fixture{db: db, term: term, endsAt: now.Add(-time.Hour), now: now}
+}
+
+func (f *fixture) createUser(t *testing.T, studentID string) *models.User {
+	t.Helper()
+
+	user, err := f.db.CreateUser(studentID, "Closeout "+studentID, studentID+"@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func (f *fixture) exec(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+
+	if _, err := f.db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func (f *fixture) endTerm(t *testing.T) {
+	t.Helper()
+
+	starts := f.endsAt.AddDate(0, 0, -120)
+	f.exec(t, `UPDATE terms SET starts_at = ?, ends_at = ? WHERE id = ?`, starts, f.endsAt, f.term.ID)
+	f.exec(t, `INSERT INTO terms (name, kind, starts_at, ends_at) VALUES (?, ?, ?, ?)`,
+		"Next "+f.term.Name, models.TermKindSemester, f.endsAt, f.endsAt.AddDate(0, 0, 120))
+	f.term.StartsAt, f.term.EndsAt = starts, f.endsAt
+}
+
+func (f *fixture) spend(t *testing.T, userID int64, amount float64, at time.Time) {
+	t.Helper()
+
+	tx := models.Transaction{Amount: amount, Location: "Market Cafe", Description: "Lunch", TransactionDate: at}
+	if _, _, err := f.db.ImportTransactions(userID, []models.Transaction{tx}); err != nil {
+		t.Fatalf("ImportTransactions: %v", err)
+	}
+}
+
+func account(t *testing.T, report *Report, userID int64) Account {
+	t.Helper()
+
+	for _, a := range report.Accounts {
+		if a.UserID == userID {
+			return a
+		}
+	}
+	t.Fatalf("report has no account for user %d", userID)
+	return Account{}
+}
+
+func TestRunSettlesBalanceAsOfTermEnd(t *testing.T) {
+	f := newFixture(t)
+	user := f.createUser(t, "50000001")
+	f.endTerm(t)
+
+	f.spend(t, user.ID, 1000, f.endsAt.Add(-24*time.Hour))
+	f.spend(t, user.ID, 50, f.endsAt.Add(30*time.Minute))
+	deposit, err := f.db.CreateDeposit(user.ID, models.DepositSourceTopUp, 100, "test", "Top-up", nil)
+	if err != nil {
+		t.Fatalf("CreateDeposit: %v", err)
+	}
+	if _, err := f.db.SettleDeposit(deposit.Reference, "test_"+deposit.Reference); err != nil {
+		t.Fatalf("SettleDeposit: %v", err)
+	}
+
+	preview, err := Run(f.db, f.term.Name, Options{DryRun: true, Now: f.now})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	report, err := Run(f.db, f.term.Name, Options{Now: f.now})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, r := range []*Report{preview, report} {
+		a := account(t, r, user.ID)
+		if a.BalanceBefore != 500 || a.RolledOver != 300 || a.Forfeited != 200 || a.BalanceAfter != 300 {
+			t.Errorf("dry_run=%v: account = %+v, want balance 500, rollover 300, forfeit 200", r.DryRun, a)
+		}
+	}
+
+	balance, err := f.db.GetUserBalance(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserBalance: %v", err)
+	}
+	if balance.CurrentBalance != 350 {
+		t.Fatalf("current balance = %.2f, want 350.00 (next term activity kept)", balance.CurrentBalance)
+	}
+}
+
+func TestRunConvertsOnlyClosedTermSwipes(t *testing.T) {
+	f := newFixture(t)
+	plans, err := f.db.GetMealPlans(true)
+	if err != nil {
+		t.Fatalf("GetMealPlans: %v", err)
+	}
+	var block *models.MealPlan
+	for i := range plans {
+		if plans[i].Term == f.term.Kind && plans[i].SwipeReset == models.SwipeResetTerm && plans[i].MealSwipes > 0 {
+			block = &plans[i]
+		}
+	}
+	if block == nil {
+		t.Fatal("no term swipe plan seeded")
+	}
+
+	leftover := f.createUser(t, "50000002")
+	refilled := f.createUser(t, "50000003")
+	f.endTerm(t)
+	for _, id := range []int64{leftover.ID, refilled.ID} {
+		f.exec(t, `UPDATE user_meal_plans SET meal_plan_id = ? WHERE user_id = ?`, block.ID, id)
+		f.exec(t, `UPDATE balances SET swipes_remaining = 10, swipe_allowance = ?, swipe_reset = ?, swipes_reset_at = ? WHERE user_id = ?`,
+			block.MealSwipes, block.SwipeReset, f.endsAt, id)
+	}
+	if err := f.db.ResetSwipesIfDue(refilled.ID, f.now); err != nil {
+		t.Fatalf("ResetSwipesIfDue: %v", err)
+	}
+
+	report, err := Run(f.db, f.term.Name, Options{Now: f.now})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	tests := []struct {
+		user      *models.User
+		swipes    int
+		converted float64
+		remaining int
+	}{
+		{leftover, 10, 10 * block.ConversionRate, 0},
+		{refilled, 0, 0, block.MealSwipes},
+	}
+	for _, tt := range tests {
+		a := account(t, report, tt.user.ID)
+		if a.SwipesRemaining != tt.swipes || a.Converted != tt.converted {
+			t.Errorf("user %s: swipes %d converted %.2f, want %d and %.2f", tt.user.StudentID, a.SwipesRemaining, a.Converted, tt.swipes, tt.converted)
+		}
+		balance, err := f.db.GetUserBalance(tt.user.ID)
+		if err != nil {
+			t.Fatalf("GetUserBalance: %v", err)
+		}
+		if balance.SwipesRemaining != tt.remaining {
+			t.Errorf("user %s: swipes remaining after closeout = %d, want %d", tt.user.StudentID, balance.SwipesRemaining, tt.remaining)
+		}
+	}
+}
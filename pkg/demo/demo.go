@@ -0,0 +1,40 @@
+package demo
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const Banner = "Demo mode: accounts, balances and transactions are sample data and may be reset at any time."
+
+var (
+	ErrUnavailable = errors.New("demo mode is not compiled into this build; rebuild with -tags demo")
+	ErrProduction  = errors.New("demo mode cannot be enabled when APP_ENV=production")
+)
+
+type Account struct {
+	StudentID string
+	Name      string
+	Email     string
+	Password  string
+	Role      string
+}
+
+type Result struct {
+	Accounts     []Account
+	Created      int
+	Transactions int
+}
+
+func Seed(db *models.DB, now time.Time) (*Result, error) {
+	if os.Getenv("APP_ENV") == "production" {
+		return nil, ErrProduction
+	}
+	if !Available {
+		return nil, ErrUnavailable
+	}
+	return seed(db, now)
+}
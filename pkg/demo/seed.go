@@ -0,0 +1,166 @@
+//go:build demo
+
+package demo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	Available = true
+
+	historyDays = 28
+)
+
+type profile struct {
+	Account
+	appetite float64
+	topUp    float64
+}
+
+var profiles = []profile{
+	{Account: Account{StudentID: "10000001", Name: "Alice Johnson", Email: "alice@example.com", Password: "password123", Role: auth.RoleStudent}, appetite: 0.75, topUp: 50},
+	{Account: Account{StudentID: "10000002", Name: "Bob Smith", Email: "bob@example.com", Password: "password123", Role: auth.RoleStudent}, appetite: 0.45},
+	{Account: Account{StudentID: "10000003", Name: "Charlie Davis", Email: "charlie@example.com", Password: "password123", Role: auth.RoleStudent}, appetite: 0.9, topUp: 25},
+	{Account: Account{StudentID: "10000004", Name: "Diana Miller", Email: "diana@example.com", Password: "password123", Role: auth.RoleStaff}, appetite: 0.3},
+	{Account: Account{StudentID: "10000005", Name: "Ethan Wilson", Email: "ethan@example.com", Password: "password123", Role: auth.RoleAdmin}, appetite: 0.55},
+}
+
+type purchase struct {
+	location    string
+	description string
+	price       float64
+}
+
+var meals = []struct {
+	hour      int
+	purchases []purchase
+}{
+	{hour: 8, purchases: []purchase{
+		{"Market Cafe", "Belgian Waffle", 7.50},
+		{"Market Cafe", "Avocado Toast", 8.99},
+		{"Crossroads Cafe", "Greek Yogurt Parfait", 5.99},
+		{"Crossroads Cafe", "Coffee and Bagel", 4.75},
+		{"Lone Mountain Cafe", "Fruit Smoothie Bowl", 7.99},
+	}},
+	{hour: 12, purchases: []purchase{
+		{"Market Cafe", "Quinoa Bowl", 11.25},
+		{"Crossroads Cafe", "Caprese Panini", 9.99},
+		{"Crossroads Cafe", "Turkey Wrap and Chips", 8.50},
+		{"Market Cafe", "Burrito Bowl", 10.75},
+	}},
+	{hour: 18, purchases: []purchase{
+		{"Lone Mountain Cafe", "Mushroom Risotto", 13.50},
+		{"Lone Mountain Cafe", "Grilled Salmon", 16.99},
+		{"Market Cafe", "Margherita Pizza", 12.50},
+		{"Market Cafe", "Beef Stir-Fry", 14.25},
+		{"Lone Mountain Cafe", "Pasta Primavera", 13.99},
+	}},
+}
+
+func seed(db *models.DB, now time.Time) (*Result, error) {
+	term, err := db.EnsureTerm(now)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current term: %w", err)
+	}
+
+	start := now.AddDate(0, 0, -historyDays)
+	if start.Before(term.StartsAt) {
+		start = term.StartsAt
+	}
+
+	result := &Result{}
+	for _, p := range profiles {
+		result.Accounts = append(result.Accounts, p.Account)
+
+		existing, err := db.GetUserByStudentID(p.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			continue
+		}
+
+		count, err := seedAccount(db, p, start, now)
+		if err != nil {
+			return nil, fmt.Errorf("error seeding demo account %s: %w", p.StudentID, err)
+		}
+		result.Created++
+		result.Transactions += count
+	}
+	return result, nil
+}
+
+func seedAccount(db *models.DB, p profile, start, now time.Time) (int, error) {
+	user, err := db.CreateUser(p.StudentID, p.Name, p.Email, p.Password)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.MarkEmailVerified(user.ID, now); err != nil {
+		return 0, err
+	}
+	if p.Role != models.DefaultRole {
+		if _, err := db.GrantUserRole(user.ID, p.Role); err != nil {
+			return 0, err
+		}
+	}
+
+	if p.topUp > 0 {
+		deposit, err := db.CreateDeposit(user.ID, models.DepositSourceTopUp, p.topUp, "demo", "Demo top-up", nil)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.SettleDeposit(deposit.Reference, "demo_"+deposit.Reference); err != nil {
+			return 0, err
+		}
+	}
+
+	transactions := history(p, start, now)
+	if len(transactions) == 0 {
+		return 0, nil
+	}
+	_, duplicates, err := db.ImportTransactions(user.ID, transactions)
+	if err != nil {
+		return 0, err
+	}
+	return len(transactions) - len(duplicates), nil
+}
+
+func history(p profile, start, now time.Time) []models.Transaction {
+	h := fnv.New64a()
+	h.Write([]byte(p.StudentID))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	var transactions []models.Transaction
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, now.Location())
+	for ; day.Before(now); day = day.AddDate(0, 0, 1) {
+		appetite := p.appetite
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			appetite /= 2
+		}
+
+		for _, meal := range meals {
+			if rng.Float64() >= appetite {
+				continue
+			}
+			at := day.Add(time.Duration(meal.hour)*time.Hour + time.Duration(rng.Intn(90))*time.Minute)
+			if at.Before(start) || !at.Before(now) {
+				continue
+			}
+			item := meal.purchases[rng.Intn(len(meal.purchases))]
+			transactions = append(transactions, models.Transaction{
+				Amount:          item.price,
+				Location:        item.location,
+				Description:     item.description,
+				TransactionDate: at,
+			})
+		}
+	}
+	return transactions
+}
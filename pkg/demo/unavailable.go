@@ -0,0 +1,15 @@
+//go:build !demo
+
+package demo
+
+import (
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const Available = false
+
+func seed(db *models.DB, now time.Time) (*Result, error) {
+	return nil, ErrUnavailable
+}
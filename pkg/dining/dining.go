@@ -0,0 +1,560 @@
+package dining
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrUnknownMealPeriod = errors.New("unknown meal period")
+	ErrUnknownLocation   = errors.New("unknown location")
+)
+
+type Location struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Icon        string    `json:"icon"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type MealPeriod struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+}
+
+type MenuItem struct {
+	ID                  int64            `json:"id"`
+	Name                string           `json:"name"`
+	Description         string           `json:"description"`
+	Price               float64          `json:"price"`
+	MealType            string           `json:"meal_type"`
+	Location            string           `json:"location"`
+	LocationID          int64            `json:"location_id"`
+	DietaryRestrictions []string         `json:"dietary_restrictions"`
+	Nutrition           models.Nutrition `json:"nutrition"`
+	Active              bool             `json:"active"`
+	CreatedAt           time.Time        `json:"created_at"`
+	UpdatedAt           time.Time        `json:"updated_at"`
+}
+
+type MenuFilter struct {
+	MealType            string
+	DietaryRestrictions []string
+	Location            string
+	MinPrice            float64
+	MaxPrice            float64
+	Search              string
+	IncludeInactive     bool
+}
+
+const DefaultTaxRate = 0.08625
+
+type Store struct {
+	db           *models.DB
+	TaxRate      float64
+	ClosedPolicy string
+}
+
+func NewStore(db *models.DB) (*Store, error) {
+	s := &Store{db: db, TaxRate: DefaultTaxRate, ClosedPolicy: ClosedWarn}
+	if err := s.createTables(); err != nil {
+		return nil, fmt.Errorf("error creating dining tables: %w", err)
+	}
+	if err := s.seed(); err != nil {
+		return nil, fmt.Errorf("error seeding dining menu: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Store) createTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS dining_locations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			icon TEXT NOT NULL DEFAULT 'fa-utensils',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS meal_periods (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			starts_at TEXT NOT NULL,
+			ends_at TEXT NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS menu_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			price REAL NOT NULL,
+			meal_period_id INTEGER NOT NULL,
+			location_id INTEGER NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (name, location_id),
+			FOREIGN KEY (meal_period_id) REFERENCES meal_periods (id),
+			FOREIGN KEY (location_id) REFERENCES dining_locations (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS menu_item_dietary (
+			menu_item_id INTEGER NOT NULL,
+			restriction TEXT NOT NULL,
+			PRIMARY KEY (menu_item_id, restriction),
+			FOREIGN KEY (menu_item_id) REFERENCES menu_items (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS dining_discounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT UNIQUE NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			percent_off REAL NOT NULL DEFAULT 0,
+			amount_off REAL NOT NULL DEFAULT 0,
+			min_subtotal REAL NOT NULL DEFAULT 0,
+			location_id INTEGER,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			expires_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (location_id) REFERENCES dining_locations (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_dietary_preferences (
+			user_id INTEGER NOT NULL,
+			restriction TEXT NOT NULL,
+			PRIMARY KEY (user_id, restriction),
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_nutrition_goals (
+			user_id INTEGER PRIMARY KEY,
+			calories REAL NOT NULL DEFAULT 0,
+			protein_g REAL NOT NULL DEFAULT 0,
+			carbs_g REAL NOT NULL DEFAULT 0,
+			fat_g REAL NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS location_hours (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			location_id INTEGER NOT NULL,
+			term TEXT NOT NULL DEFAULT '',
+			weekday INTEGER NOT NULL,
+			opens_at TEXT NOT NULL,
+			closes_at TEXT NOT NULL,
+			FOREIGN KEY (location_id) REFERENCES dining_locations (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS location_hour_overrides (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			location_id INTEGER,
+			date TEXT NOT NULL,
+			closed BOOLEAN NOT NULL DEFAULT 1,
+			opens_at TEXT NOT NULL DEFAULT '',
+			closes_at TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (location_id) REFERENCES dining_locations (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range []string{"calories", "protein_g", "carbs_g", "fat_g"} {
+		if err := s.db.AddColumnIfMissing("menu_items", column, "REAL NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func normalizeRestrictions(restrictions []string) []string {
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, r := range restrictions {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" || seen[r] {
+			continue
+		}
+		seen[r] = true
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Store) ListLocations() ([]Location, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, description, icon, created_at
+		FROM dining_locations
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []Location{}
+	for rows.Next() {
+		var l Location
+		if err := rows.Scan(&l.ID, &l.Name, &l.Description, &l.Icon, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning location: %w", err)
+		}
+		locations = append(locations, l)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating locations: %w", err)
+	}
+
+	return locations, nil
+}
+
+func (s *Store) GetLocationByName(name string) (*Location, error) {
+	var l Location
+	err := s.db.QueryRow(`
+		SELECT id, name, description, icon, created_at
+		FROM dining_locations
+		WHERE name = ?
+	`, name).Scan(&l.ID, &l.Name, &l.Description, &l.Icon, &l.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting location: %w", err)
+	}
+	return &l, nil
+}
+
+func (s *Store) CreateLocation(l *Location) (*Location, error) {
+	if l.Icon == "" {
+		l.Icon = "fa-utensils"
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO dining_locations (name, description, icon, created_at)
+		VALUES (?, ?, ?, ?)
+	`, l.Name, l.Description, l.Icon, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating location: %w", err)
+	}
+
+	return s.GetLocationByName(l.Name)
+}
+
+func (s *Store) ListMealPeriods() ([]MealPeriod, error) {
+	rows, err := s.db.Query(`SELECT id, name, starts_at, ends_at FROM meal_periods ORDER BY starts_at`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting meal periods: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []MealPeriod{}
+	for rows.Next() {
+		var p MealPeriod
+		if err := rows.Scan(&p.ID, &p.Name, &p.StartsAt, &p.EndsAt); err != nil {
+			return nil, fmt.Errorf("error scanning meal period: %w", err)
+		}
+		periods = append(periods, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating meal periods: %w", err)
+	}
+
+	return periods, nil
+}
+
+const menuItemSelect = `
+	SELECT m.id, m.name, m.description, m.price, p.name, l.name, l.id, m.active, m.created_at, m.updated_at,
+		m.calories, m.protein_g, m.carbs_g, m.fat_g,
+		COALESCE((SELECT GROUP_CONCAT(restriction) FROM menu_item_dietary WHERE menu_item_id = m.id), '')
+	FROM menu_items m
+	JOIN meal_periods p ON p.id = m.meal_period_id
+	JOIN dining_locations l ON l.id = m.location_id`
+
+func scanMenuItem(row interface{ Scan(...interface{}) error }) (*MenuItem, error) {
+	var item MenuItem
+	var restrictions string
+	err := row.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &item.MealType, &item.Location,
+		&item.LocationID, &item.Active, &item.CreatedAt, &item.UpdatedAt, &item.Nutrition.Calories,
+		&item.Nutrition.Protein, &item.Nutrition.Carbs, &item.Nutrition.Fat, &restrictions)
+	if err != nil {
+		return nil, err
+	}
+	item.DietaryRestrictions = normalizeRestrictions(strings.Split(restrictions, ","))
+	return &item, nil
+}
+
+func (s *Store) ListMenu(filter MenuFilter) ([]MenuItem, error) {
+	query := menuItemSelect + ` WHERE 1 = 1`
+	var args []interface{}
+
+	if !filter.IncludeInactive {
+		query += ` AND m.active = 1`
+	}
+	if filter.MealType != "" {
+		query += ` AND p.name = ?`
+		args = append(args, strings.ToLower(filter.MealType))
+	}
+	if filter.Location != "" {
+		query += ` AND l.name = ?`
+		args = append(args, filter.Location)
+	}
+	if filter.MinPrice > 0 {
+		query += ` AND m.price >= ?`
+		args = append(args, filter.MinPrice)
+	}
+	if filter.MaxPrice > 0 {
+		query += ` AND m.price <= ?`
+		args = append(args, filter.MaxPrice)
+	}
+	if filter.Search != "" {
+		query += ` AND m.name LIKE ?`
+		args = append(args, "%"+filter.Search+"%")
+	}
+	if restrictions := normalizeRestrictions(filter.DietaryRestrictions); len(restrictions) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(restrictions)), ",")
+		query += ` AND m.id IN (
+			SELECT menu_item_id FROM menu_item_dietary
+			WHERE restriction IN (` + placeholders + `)
+			GROUP BY menu_item_id
+			HAVING COUNT(DISTINCT restriction) = ?)`
+		for _, r := range restrictions {
+			args = append(args, r)
+		}
+		args = append(args, len(restrictions))
+	}
+	query += ` ORDER BY p.starts_at, m.name`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting menu: %w", err)
+	}
+	defer rows.Close()
+
+	items := []MenuItem{}
+	for rows.Next() {
+		item, err := scanMenuItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning menu item: %w", err)
+		}
+		items = append(items, *item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating menu: %w", err)
+	}
+
+	return items, nil
+}
+
+func (s *Store) GetMenuItem(id int64) (*MenuItem, error) {
+	item, err := scanMenuItem(s.db.QueryRow(menuItemSelect+` WHERE m.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting menu item: %w", err)
+	}
+	return item, nil
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func resolveIDs(q execer, item *MenuItem, createLocation bool) (periodID, locationID int64, err error) {
+	err = q.QueryRow(`SELECT id FROM meal_periods WHERE name = ?`, strings.ToLower(item.MealType)).Scan(&periodID)
+	if err == sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("%w %q", ErrUnknownMealPeriod, item.MealType)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = q.QueryRow(`SELECT id FROM dining_locations WHERE name = ?`, item.Location).Scan(&locationID)
+	if err == sql.ErrNoRows && createLocation {
+		err = q.QueryRow(`
+			INSERT INTO dining_locations (name, created_at) VALUES (?, ?) RETURNING id
+		`, item.Location, time.Now()).Scan(&locationID)
+	}
+	if err == sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("%w %q", ErrUnknownLocation, item.Location)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return periodID, locationID, nil
+}
+
+func writeRestrictions(q execer, itemID int64, restrictions []string) error {
+	if _, err := q.Exec(`DELETE FROM menu_item_dietary WHERE menu_item_id = ?`, itemID); err != nil {
+		return err
+	}
+	for _, r := range normalizeRestrictions(restrictions) {
+		if _, err := q.Exec(`INSERT INTO menu_item_dietary (menu_item_id, restriction) VALUES (?, ?)`, itemID, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upsertMenuItem(q execer, item *MenuItem, createLocation bool) (id int64, created bool, err error) {
+	periodID, locationID, err := resolveIDs(q, item, createLocation)
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = q.QueryRow(`SELECT id FROM menu_items WHERE name = ? AND location_id = ?`, item.Name, locationID).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		created = true
+		err = q.QueryRow(`
+			INSERT INTO menu_items (name, description, price, meal_period_id, location_id, active, created_at, updated_at,
+				calories, protein_g, carbs_g, fat_g)
+			VALUES (?, ?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, item.Name, item.Description, item.Price, periodID, locationID, time.Now(), time.Now(),
+			item.Nutrition.Calories, item.Nutrition.Protein, item.Nutrition.Carbs, item.Nutrition.Fat).Scan(&id)
+	case err == nil:
+		_, err = q.Exec(`
+			UPDATE menu_items
+			SET description = ?, price = ?, meal_period_id = ?, active = 1, updated_at = ?,
+				calories = ?, protein_g = ?, carbs_g = ?, fat_g = ?
+			WHERE id = ?
+		`, item.Description, item.Price, periodID, time.Now(), item.Nutrition.Calories,
+			item.Nutrition.Protein, item.Nutrition.Carbs, item.Nutrition.Fat, id)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := writeRestrictions(q, id, item.DietaryRestrictions); err != nil {
+		return 0, false, err
+	}
+
+	return id, created, nil
+}
+
+func (s *Store) CreateMenuItem(item *MenuItem) (*MenuItem, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, _, err := upsertMenuItem(tx, item, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return s.GetMenuItem(id)
+}
+
+func (s *Store) UpdateMenuItem(id int64, item *MenuItem) (*MenuItem, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	periodID, locationID, err := resolveIDs(tx, item, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE menu_items
+		SET name = ?, description = ?, price = ?, meal_period_id = ?, location_id = ?, active = ?, updated_at = ?,
+			calories = ?, protein_g = ?, carbs_g = ?, fat_g = ?
+		WHERE id = ?
+	`, item.Name, item.Description, item.Price, periodID, locationID, item.Active, time.Now(),
+		item.Nutrition.Calories, item.Nutrition.Protein, item.Nutrition.Carbs, item.Nutrition.Fat, id)
+	if err != nil {
+		return nil, fmt.Errorf("error updating menu item: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	if err := writeRestrictions(tx, id, item.DietaryRestrictions); err != nil {
+		return nil, fmt.Errorf("error updating dietary restrictions: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return s.GetMenuItem(id)
+}
+
+func (s *Store) DeactivateMenuItem(id int64) error {
+	result, err := s.db.Exec(`UPDATE menu_items SET active = 0, updated_at = ? WHERE id = ?`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error deactivating menu item: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,734 @@
+package dining
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/auth"
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const maxImportSize = 5 << 20
+
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) GetMenu(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := MenuFilter{
+		MealType: q.Get("meal_type"),
+		Location: q.Get("location"),
+		Search:   q.Get("q"),
+	}
+	if v := q.Get("dietary"); v != "" {
+		filter.DietaryRestrictions = strings.Split(v, ",")
+	}
+
+	var err error
+	if v := q.Get("min_price"); v != "" {
+		if filter.MinPrice, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Invalid min_price", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("max_price"); v != "" {
+		if filter.MaxPrice, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Invalid max_price", http.StatusBadRequest)
+			return
+		}
+	}
+
+	items, err := h.store.ListMenu(filter)
+	if err != nil {
+		http.Error(w, "Failed to get menu", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+type locationWithStatus struct {
+	Location
+	OpenStatus
+}
+
+func (h *Handler) GetLocations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	openOnly, _ := strconv.ParseBool(r.URL.Query().Get("open_now"))
+
+	locations, err := h.store.ListLocations()
+	if err != nil {
+		http.Error(w, "Failed to get locations", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	withStatus := []locationWithStatus{}
+	for _, l := range locations {
+		status, err := h.store.LocationStatus(l.ID, now)
+		if err != nil {
+			http.Error(w, "Failed to get location hours", http.StatusInternalServerError)
+			return
+		}
+		if openOnly && !(status.Known && status.Open) {
+			continue
+		}
+		withStatus = append(withStatus, locationWithStatus{l, *status})
+	}
+
+	periods, err := h.store.ListMealPeriods()
+	if err != nil {
+		http.Error(w, "Failed to get meal periods", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"locations":    withStatus,
+		"meal_periods": periods,
+	})
+}
+
+func (h *Handler) AdminCreateLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var l Location
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.store.GetLocationByName(l.Name)
+	if err != nil {
+		http.Error(w, "Failed to create location", http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		http.Error(w, "Location already exists", http.StatusConflict)
+		return
+	}
+
+	created, err := h.store.CreateLocation(&l)
+	if err != nil {
+		http.Error(w, "Failed to create location", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+type menuItemRequest struct {
+	Name                string           `json:"name"`
+	Description         string           `json:"description"`
+	Price               Price            `json:"price"`
+	MealType            string           `json:"meal_type"`
+	Location            string           `json:"location"`
+	DietaryRestrictions []string         `json:"dietary_restrictions"`
+	Nutrition           models.Nutrition `json:"nutrition"`
+	Active              *bool            `json:"active"`
+}
+
+func (req *menuItemRequest) toMenuItem() *MenuItem {
+	item := &MenuItem{
+		Name:                strings.TrimSpace(req.Name),
+		Description:         req.Description,
+		Price:               float64(req.Price),
+		MealType:            strings.ToLower(strings.TrimSpace(req.MealType)),
+		Location:            strings.TrimSpace(req.Location),
+		DietaryRestrictions: req.DietaryRestrictions,
+		Nutrition:           req.Nutrition,
+		Active:              true,
+	}
+	if req.Active != nil {
+		item.Active = *req.Active
+	}
+	return item
+}
+
+func writeStoreError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Menu item not found", http.StatusNotFound)
+	case errors.Is(err, ErrUnknownMealPeriod), errors.Is(err, ErrUnknownLocation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) AdminMenu(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		items, err := h.store.ListMenu(MenuFilter{IncludeInactive: true})
+		if err != nil {
+			http.Error(w, "Failed to get menu", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	case http.MethodPost:
+		var req menuItemRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		item := req.toMenuItem()
+		if err := ValidateMenuItem(item); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		created, err := h.store.CreateMenuItem(item)
+		if err != nil {
+			writeStoreError(w, err, "Failed to create menu item")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) AdminMenuItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/admin/dining/menu/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid menu item ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		item, err := h.store.GetMenuItem(id)
+		if err != nil {
+			writeStoreError(w, err, "Failed to get menu item")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(item)
+	case http.MethodPut:
+		var req menuItemRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		item := req.toMenuItem()
+		if err := ValidateMenuItem(item); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		updated, err := h.store.UpdateMenuItem(id, item)
+		if err != nil {
+			writeStoreError(w, err, "Failed to update menu item")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+	case http.MethodDelete:
+		if err := h.store.DeactivateMenuItem(id); err != nil {
+			writeStoreError(w, err, "Failed to delete menu item")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) AdminImportMenu(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var body io.Reader = r.Body
+	contentType := r.Header.Get("Content-Type")
+	format := r.URL.Query().Get("format")
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportSize); err != nil {
+			http.Error(w, "Invalid upload", http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" && strings.HasSuffix(strings.ToLower(header.Filename), ".csv") {
+			format = "csv"
+		}
+	} else if format == "" && strings.HasPrefix(contentType, "text/csv") {
+		format = "csv"
+	}
+
+	var items []MenuItem
+	var err error
+	if format == "csv" {
+		items, err = ParseCSV(body)
+	} else {
+		items, err = ParseJSON(body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	report, err := h.store.ImportMenu(items, dryRun)
+	if err != nil {
+		http.Error(w, "Failed to import menu", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(report.Errors) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+func writeOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrEmptyOrder), errors.Is(err, ErrInvalidQuantity), errors.Is(err, ErrItemUnavailable),
+		errors.Is(err, ErrMixedLocations), errors.Is(err, ErrInvalidDiscount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrTermClosed):
+		http.Error(w, "The current term is closed to new transactions", http.StatusConflict)
+	default:
+		http.Error(w, "Failed to place order", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.store.PriceOrder(req, time.Now())
+	if err != nil {
+		writeOrderError(w, err)
+		return
+	}
+
+	var warnings []string
+	warning, err := h.store.CheckLocationOpen(quote.Location, time.Now())
+	switch {
+	case errors.Is(err, ErrLocationClosed):
+		http.Error(w, warning, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to check location hours", http.StatusInternalServerError)
+		return
+	case warning != "" && !req.ConfirmClosed:
+		http.Error(w, warning+"; resubmit with confirm_closed to order anyway", http.StatusConflict)
+		return
+	case warning != "":
+		warnings = append(warnings, warning)
+	}
+
+	if preview, _ := strconv.ParseBool(r.URL.Query().Get("preview")); preview {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(quote)
+		return
+	}
+
+	settings, err := h.store.db.GetBudgetSettings(userID)
+	if err != nil {
+		http.Error(w, "Failed to get budget settings", http.StatusInternalServerError)
+		return
+	}
+
+	balance, err := h.store.db.GetUserBalance(userID)
+	if err != nil {
+		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
+		return
+	}
+
+	if settings.StrictBudget && balance.CurrentBalance < quote.Total {
+		http.Error(w, "Transaction exceeds available balance with strict budget enabled", http.StatusForbidden)
+		return
+	}
+
+	tx, err := h.store.WithAudit(auth.AuditMeta(r)).PlaceOrder(userID, quote)
+	if err != nil {
+		writeOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		Transaction *models.Transaction `json:"transaction"`
+		Quote       *Quote              `json:"quote"`
+		Warnings    []string            `json:"warnings,omitempty"`
+	}{tx, quote, warnings})
+}
+
+func (h *Handler) AdminDiscounts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		discounts, err := h.store.ListDiscounts()
+		if err != nil {
+			http.Error(w, "Failed to get discounts", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(discounts)
+	case http.MethodPost:
+		var req struct {
+			Discount
+			Location string `json:"location"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		d := req.Discount
+		if normalizeCode(d.Code) == "" {
+			http.Error(w, "Code is required", http.StatusBadRequest)
+			return
+		}
+		if d.PercentOff < 0 || d.PercentOff > 100 || d.AmountOff < 0 || d.MinSubtotal < 0 {
+			http.Error(w, "Discount values are out of range", http.StatusBadRequest)
+			return
+		}
+		if d.PercentOff == 0 && d.AmountOff == 0 {
+			http.Error(w, "Discount must set percent_off or amount_off", http.StatusBadRequest)
+			return
+		}
+
+		d.LocationID = nil
+		if req.Location != "" {
+			location, err := h.store.GetLocationByName(req.Location)
+			if err != nil {
+				http.Error(w, "Failed to create discount", http.StatusInternalServerError)
+				return
+			}
+			if location == nil {
+				http.Error(w, "Unknown location", http.StatusBadRequest)
+				return
+			}
+			d.LocationID = &location.ID
+		}
+
+		existing, err := h.store.GetDiscountByCode(d.Code)
+		if err != nil {
+			http.Error(w, "Failed to create discount", http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			http.Error(w, "Discount code already exists", http.StatusConflict)
+			return
+		}
+
+		created, err := h.store.CreateDiscount(&d)
+		if err != nil {
+			http.Error(w, "Failed to create discount", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := RecommendOptions{MealType: q.Get("meal_type")}
+	if q.Has("dietary") {
+		opts.DietaryRestrictions = strings.Split(q.Get("dietary"), ",")
+	}
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	recommendations, err := h.store.Recommend(userID, opts)
+	if err != nil {
+		http.Error(w, "Failed to get recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recommendations)
+}
+
+func (h *Handler) DietaryPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var preferences []string
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		preferences, err = h.store.GetDietaryPreferences(userID)
+	case http.MethodPut, http.MethodPost:
+		var req struct {
+			DietaryRestrictions []string `json:"dietary_restrictions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		preferences, err = h.store.SetDietaryPreferences(userID, req.DietaryRestrictions)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update dietary preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"dietary_restrictions": preferences})
+}
+
+func (h *Handler) NutritionGoals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var goals models.Nutrition
+		if err := json.NewDecoder(r.Body).Decode(&goals); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if goals.Calories < 0 || goals.Protein < 0 || goals.Carbs < 0 || goals.Fat < 0 {
+			http.Error(w, "Goals must not be negative", http.StatusBadRequest)
+			return
+		}
+		if err := h.store.SetNutritionGoals(userID, goals); err != nil {
+			http.Error(w, "Failed to update nutrition goals", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	goals, err := h.store.GetNutritionGoals(userID)
+	if err != nil {
+		http.Error(w, "Failed to get nutrition goals", http.StatusInternalServerError)
+		return
+	}
+	if goals == nil {
+		goals = &models.Nutrition{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goals)
+}
+
+func (h *Handler) NutritionAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	period := q.Get("period")
+	if period == "" {
+		period = PeriodDay
+	}
+
+	at := time.Now()
+	if v := q.Get("date"); v != "" {
+		var err error
+		if at, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.store.NutritionReport(userID, period, at)
+	if errors.Is(err, ErrInvalidPeriod) {
+		http.Error(w, "Period must be day or week", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get nutrition analytics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func writeHoursError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidHours), errors.Is(err, ErrUnknownLocation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Override not found", http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) AdminHours(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		location, err := h.store.GetLocationByName(r.URL.Query().Get("location"))
+		if err != nil {
+			http.Error(w, "Failed to get hours", http.StatusInternalServerError)
+			return
+		}
+		if location == nil {
+			http.Error(w, "Unknown location", http.StatusNotFound)
+			return
+		}
+
+		schedules, err := h.store.GetSchedules(location.ID)
+		if err != nil {
+			http.Error(w, "Failed to get hours", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedules)
+	case http.MethodPut, http.MethodPost:
+		var schedule Schedule
+		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		schedule.Term = strings.TrimSpace(schedule.Term)
+
+		if err := h.store.SetSchedule(&schedule); err != nil {
+			writeHoursError(w, err, "Failed to update hours")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedule)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) AdminHoursOverrides(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		overrides, err := h.store.ListOverrides(time.Now())
+		if err != nil {
+			http.Error(w, "Failed to get overrides", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(overrides)
+	case http.MethodPost:
+		var o HoursOverride
+		if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		created, err := h.store.CreateOverride(&o)
+		if err != nil {
+			writeHoursError(w, err, "Failed to create override")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid override ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.store.DeleteOverride(id); err != nil {
+			writeHoursError(w, err, "Failed to delete override")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
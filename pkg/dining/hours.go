@@ -0,0 +1,355 @@
+package dining
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ClosedWarn   = "warn"
+	ClosedReject = "reject"
+)
+
+var (
+	ErrLocationClosed = errors.New("location is closed")
+	ErrInvalidHours   = errors.New("invalid hours")
+)
+
+type Hours struct {
+	Weekday  string `json:"weekday"`
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+}
+
+type Schedule struct {
+	Location string  `json:"location"`
+	Term     string  `json:"term,omitempty"`
+	Hours    []Hours `json:"hours"`
+}
+
+type HoursOverride struct {
+	ID       int64  `json:"id"`
+	Location string `json:"location,omitempty"`
+	Date     string `json:"date"`
+	Closed   bool   `json:"closed"`
+	OpensAt  string `json:"opens_at,omitempty"`
+	ClosesAt string `json:"closes_at,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+type OpenStatus struct {
+	Known    bool   `json:"hours_known"`
+	Open     bool   `json:"open_now"`
+	OpensAt  string `json:"opens_at,omitempty"`
+	ClosesAt string `json:"closes_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func ParseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) || strings.EqualFold(s, d.String()[:3]) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown weekday %q", ErrInvalidHours, s)
+}
+
+func validClock(s string) bool {
+	if s == "24:00" {
+		return true
+	}
+	_, err := time.Parse("15:04", s)
+	return err == nil && len(s) == 5
+}
+
+func validateWindow(opensAt, closesAt string) error {
+	if !validClock(opensAt) || !validClock(closesAt) {
+		return fmt.Errorf("%w: times must be HH:MM", ErrInvalidHours)
+	}
+	if opensAt >= closesAt {
+		return fmt.Errorf("%w: %s must be before %s", ErrInvalidHours, opensAt, closesAt)
+	}
+	return nil
+}
+
+func (s *Store) SetSchedule(schedule *Schedule) error {
+	location, err := s.GetLocationByName(schedule.Location)
+	if err != nil {
+		return err
+	}
+	if location == nil {
+		return fmt.Errorf("%w %q", ErrUnknownLocation, schedule.Location)
+	}
+
+	weekdays := make([]time.Weekday, len(schedule.Hours))
+	for i, h := range schedule.Hours {
+		if weekdays[i], err = ParseWeekday(h.Weekday); err != nil {
+			return err
+		}
+		if err := validateWindow(h.OpensAt, h.ClosesAt); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM location_hours WHERE location_id = ? AND term = ?`, location.ID, schedule.Term)
+	if err != nil {
+		return fmt.Errorf("error clearing hours: %w", err)
+	}
+
+	for i, h := range schedule.Hours {
+		_, err := tx.Exec(`
+			INSERT INTO location_hours (location_id, term, weekday, opens_at, closes_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, location.ID, schedule.Term, int(weekdays[i]), h.OpensAt, h.ClosesAt)
+		if err != nil {
+			return fmt.Errorf("error saving hours: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) GetSchedules(locationID int64) ([]Schedule, error) {
+	rows, err := s.db.Query(`
+		SELECT l.name, h.term, h.weekday, h.opens_at, h.closes_at
+		FROM location_hours h
+		JOIN dining_locations l ON l.id = h.location_id
+		WHERE h.location_id = ?
+		ORDER BY h.term, h.weekday, h.opens_at
+	`, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting hours: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		var location, term string
+		var weekday int
+		var h Hours
+		if err := rows.Scan(&location, &term, &weekday, &h.OpensAt, &h.ClosesAt); err != nil {
+			return nil, fmt.Errorf("error scanning hours: %w", err)
+		}
+		h.Weekday = time.Weekday(weekday).String()
+		if n := len(schedules); n == 0 || schedules[n-1].Term != term {
+			schedules = append(schedules, Schedule{Location: location, Term: term})
+		}
+		last := &schedules[len(schedules)-1]
+		last.Hours = append(last.Hours, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating hours: %w", err)
+	}
+
+	return schedules, nil
+}
+
+func (s *Store) CreateOverride(o *HoursOverride) (*HoursOverride, error) {
+	if _, err := time.Parse("2006-01-02", o.Date); err != nil {
+		return nil, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidHours)
+	}
+	if !o.Closed {
+		if err := validateWindow(o.OpensAt, o.ClosesAt); err != nil {
+			return nil, err
+		}
+	} else {
+		o.OpensAt, o.ClosesAt = "", ""
+	}
+
+	var locationID *int64
+	if o.Location != "" {
+		location, err := s.GetLocationByName(o.Location)
+		if err != nil {
+			return nil, err
+		}
+		if location == nil {
+			return nil, fmt.Errorf("%w %q", ErrUnknownLocation, o.Location)
+		}
+		locationID = &location.ID
+	}
+
+	err := s.db.QueryRow(`
+		INSERT INTO location_hour_overrides (location_id, date, closed, opens_at, closes_at, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, locationID, o.Date, o.Closed, o.OpensAt, o.ClosesAt, o.Reason, time.Now()).Scan(&o.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error creating override: %w", err)
+	}
+
+	return o, nil
+}
+
+func (s *Store) ListOverrides(from time.Time) ([]HoursOverride, error) {
+	rows, err := s.db.Query(`
+		SELECT o.id, COALESCE(l.name, ''), o.date, o.closed, o.opens_at, o.closes_at, o.reason
+		FROM location_hour_overrides o
+		LEFT JOIN dining_locations l ON l.id = o.location_id
+		WHERE o.date >= ?
+		ORDER BY o.date, o.id
+	`, from.In(time.Local).Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []HoursOverride{}
+	for rows.Next() {
+		var o HoursOverride
+		if err := rows.Scan(&o.ID, &o.Location, &o.Date, &o.Closed, &o.OpensAt, &o.ClosesAt, &o.Reason); err != nil {
+			return nil, fmt.Errorf("error scanning override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating overrides: %w", err)
+	}
+
+	return overrides, nil
+}
+
+func (s *Store) DeleteOverride(id int64) error {
+	result, err := s.db.Exec(`DELETE FROM location_hour_overrides WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) hoursFor(locationID int64, at time.Time) ([]Hours, error) {
+	term, err := s.db.GetTermAt(at)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := []string{""}
+	if term != nil {
+		terms = []string{term.Name, term.Kind, ""}
+	}
+
+	for _, t := range terms {
+		var count int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM location_hours WHERE location_id = ? AND term = ?
+		`, locationID, t).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("error checking hours: %w", err)
+		}
+		if count == 0 {
+			continue
+		}
+
+		rows, err := s.db.Query(`
+			SELECT opens_at, closes_at FROM location_hours
+			WHERE location_id = ? AND term = ? AND weekday = ?
+			ORDER BY opens_at
+		`, locationID, t, int(at.Weekday()))
+		if err != nil {
+			return nil, fmt.Errorf("error getting hours: %w", err)
+		}
+		defer rows.Close()
+
+		hours := []Hours{}
+		for rows.Next() {
+			h := Hours{Weekday: at.Weekday().String()}
+			if err := rows.Scan(&h.OpensAt, &h.ClosesAt); err != nil {
+				return nil, fmt.Errorf("error scanning hours: %w", err)
+			}
+			hours = append(hours, h)
+		}
+		return hours, rows.Err()
+	}
+
+	return nil, nil
+}
+
+func (s *Store) LocationStatus(locationID int64, at time.Time) (*OpenStatus, error) {
+	at = at.In(time.Local)
+	clock := at.Format("15:04")
+
+	var o HoursOverride
+	err := s.db.QueryRow(`
+		SELECT closed, opens_at, closes_at, reason
+		FROM location_hour_overrides
+		WHERE date = ? AND (location_id = ? OR location_id IS NULL)
+		ORDER BY location_id IS NULL, id DESC
+		LIMIT 1
+	`, at.Format("2006-01-02"), locationID).Scan(&o.Closed, &o.OpensAt, &o.ClosesAt, &o.Reason)
+	switch {
+	case err == nil && o.Closed:
+		return &OpenStatus{Known: true, Reason: o.Reason}, nil
+	case err == nil:
+		return &OpenStatus{
+			Known:    true,
+			Open:     clock >= o.OpensAt && clock < o.ClosesAt,
+			OpensAt:  o.OpensAt,
+			ClosesAt: o.ClosesAt,
+			Reason:   o.Reason,
+		}, nil
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("error getting override: %w", err)
+	}
+
+	hours, err := s.hoursFor(locationID, at)
+	if err != nil {
+		return nil, err
+	}
+	if hours == nil {
+		return &OpenStatus{}, nil
+	}
+
+	status := &OpenStatus{Known: true}
+	for _, h := range hours {
+		if clock >= h.OpensAt && clock < h.ClosesAt {
+			status.Open = true
+			status.OpensAt, status.ClosesAt = h.OpensAt, h.ClosesAt
+			break
+		}
+		if clock < h.OpensAt && status.OpensAt == "" {
+			status.OpensAt, status.ClosesAt = h.OpensAt, h.ClosesAt
+		}
+	}
+	return status, nil
+}
+
+func (s *Store) CheckLocationOpen(name string, at time.Time) (string, error) {
+	location, err := s.GetLocationByName(name)
+	if err != nil || location == nil {
+		return "", err
+	}
+
+	status, err := s.LocationStatus(location.ID, at)
+	if err != nil {
+		return "", err
+	}
+	if !status.Known || status.Open {
+		return "", nil
+	}
+
+	warning := fmt.Sprintf("%s is closed at this time", location.Name)
+	if status.Reason != "" {
+		warning += " (" + status.Reason + ")"
+	}
+	if s.ClosedPolicy == ClosedReject {
+		return warning, ErrLocationClosed
+	}
+	return warning, nil
+}
@@ -0,0 +1,225 @@
+package dining
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+var ErrInvalidImport = errors.New("invalid menu import")
+
+type ImportError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Errors  []ImportError `json:"errors"`
+	DryRun  bool          `json:"dry_run"`
+}
+
+type Price float64
+
+func (p *Price) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v, err := ParsePrice(s)
+		if err != nil {
+			return err
+		}
+		*p = Price(v)
+		return nil
+	}
+
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("invalid price %s", data)
+	}
+	*p = Price(f)
+	return nil
+}
+
+type importItem struct {
+	Name                string           `json:"name"`
+	Description         string           `json:"description"`
+	Price               Price            `json:"price"`
+	MealType            string           `json:"meal_type"`
+	Location            string           `json:"location"`
+	DietaryRestrictions []string         `json:"dietary_restrictions"`
+	Nutrition           models.Nutrition `json:"nutrition"`
+}
+
+func ParsePrice(s string) (float64, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "$")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return v, nil
+}
+
+func ParseJSON(r io.Reader) ([]MenuItem, error) {
+	var raw []importItem
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	items := make([]MenuItem, len(raw))
+	for i, it := range raw {
+		items[i] = MenuItem{
+			Name:                strings.TrimSpace(it.Name),
+			Description:         it.Description,
+			Price:               float64(it.Price),
+			MealType:            strings.ToLower(strings.TrimSpace(it.MealType)),
+			Location:            strings.TrimSpace(it.Location),
+			DietaryRestrictions: it.DietaryRestrictions,
+			Nutrition:           it.Nutrition,
+		}
+	}
+	return items, nil
+}
+
+func ParseCSV(r io.Reader) ([]MenuItem, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidImport)
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"name", "price", "meal_type", "location"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidImport, required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var items []MenuItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		price, err := ParsePrice(field(record, "price"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, line, err)
+		}
+
+		var restrictions []string
+		if v := field(record, "dietary_restrictions"); v != "" {
+			restrictions = strings.Split(v, ";")
+		}
+
+		var nutrition models.Nutrition
+		for _, f := range []struct {
+			column string
+			value  *float64
+		}{
+			{"calories", &nutrition.Calories},
+			{"protein_g", &nutrition.Protein},
+			{"carbs_g", &nutrition.Carbs},
+			{"fat_g", &nutrition.Fat},
+		} {
+			v := field(record, f.column)
+			if v == "" {
+				continue
+			}
+			if *f.value, err = strconv.ParseFloat(v, 64); err != nil || *f.value < 0 {
+				return nil, fmt.Errorf("%w: line %d: invalid %s %q", ErrInvalidImport, line, f.column, v)
+			}
+		}
+
+		items = append(items, MenuItem{
+			Name:                field(record, "name"),
+			Description:         field(record, "description"),
+			Price:               price,
+			MealType:            strings.ToLower(field(record, "meal_type")),
+			Location:            field(record, "location"),
+			DietaryRestrictions: restrictions,
+			Nutrition:           nutrition,
+		})
+	}
+	return items, nil
+}
+
+func ValidateMenuItem(item *MenuItem) error {
+	if item.Name == "" {
+		return errors.New("name is required")
+	}
+	if item.Location == "" {
+		return errors.New("location is required")
+	}
+	if item.MealType == "" {
+		return errors.New("meal_type is required")
+	}
+	if item.Price <= 0 {
+		return errors.New("price must be positive")
+	}
+	n := item.Nutrition
+	if n.Calories < 0 || n.Protein < 0 || n.Carbs < 0 || n.Fat < 0 {
+		return errors.New("nutrition values must not be negative")
+	}
+	return nil
+}
+
+func (s *Store) ImportMenu(items []MenuItem, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{Errors: []ImportError{}, DryRun: dryRun}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range items {
+		if err := ValidateMenuItem(&items[i]); err != nil {
+			report.Errors = append(report.Errors, ImportError{Row: i + 1, Error: err.Error()})
+			continue
+		}
+
+		_, created, err := upsertMenuItem(tx, &items[i], true)
+		if err != nil {
+			report.Errors = append(report.Errors, ImportError{Row: i + 1, Error: err.Error()})
+			continue
+		}
+		if created {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+
+	if dryRun || len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,130 @@
+package dining
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+var ErrInvalidPeriod = errors.New("period must be day or week")
+
+type NutritionDay struct {
+	Date   string           `json:"date"`
+	Totals models.Nutrition `json:"totals"`
+	Spent  float64          `json:"spent"`
+}
+
+type NutritionReport struct {
+	Period   string            `json:"period"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Totals   models.Nutrition  `json:"totals"`
+	Spent    float64           `json:"spent"`
+	Goals    *models.Nutrition `json:"goals,omitempty"`
+	Progress *models.Nutrition `json:"progress,omitempty"`
+	Days     []NutritionDay    `json:"days,omitempty"`
+}
+
+func (s *Store) GetNutritionGoals(userID int64) (*models.Nutrition, error) {
+	var n models.Nutrition
+	err := s.db.QueryRow(`
+		SELECT calories, protein_g, carbs_g, fat_g FROM user_nutrition_goals WHERE user_id = ?
+	`, userID).Scan(&n.Calories, &n.Protein, &n.Carbs, &n.Fat)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting nutrition goals: %w", err)
+	}
+	return &n, nil
+}
+
+func (s *Store) SetNutritionGoals(userID int64, goals models.Nutrition) error {
+	_, err := s.db.Exec(`
+		INSERT INTO user_nutrition_goals (user_id, calories, protein_g, carbs_g, fat_g, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			calories = excluded.calories, protein_g = excluded.protein_g,
+			carbs_g = excluded.carbs_g, fat_g = excluded.fat_g, updated_at = excluded.updated_at
+	`, userID, goals.Calories, goals.Protein, goals.Carbs, goals.Fat, time.Now())
+	if err != nil {
+		return fmt.Errorf("error saving nutrition goals: %w", err)
+	}
+	return nil
+}
+
+func percentOf(value, goal float64) float64 {
+	if goal <= 0 {
+		return 0
+	}
+	return math.Round(value / goal * 100)
+}
+
+func (s *Store) NutritionReport(userID int64, period string, at time.Time) (*NutritionReport, error) {
+	report := &NutritionReport{Period: period}
+	days := 1
+	switch period {
+	case PeriodDay:
+		report.Start, report.End = models.DayBounds(at)
+	case PeriodWeek:
+		report.Start, report.End = models.WeekBounds(at)
+		days = 7
+	default:
+		return nil, ErrInvalidPeriod
+	}
+
+	var err error
+	if report.Totals, err = s.db.GetNutritionBetween(userID, report.Start, report.End); err != nil {
+		return nil, err
+	}
+	spent, err := s.db.GetDollarsSpentBetween(userID, report.Start, report.End)
+	if err != nil {
+		return nil, err
+	}
+	report.Spent = roundCents(spent)
+
+	if period == PeriodWeek {
+		for day := report.Start; day.Before(report.End); day = day.AddDate(0, 0, 1) {
+			start, end := models.DayBounds(day)
+			totals, err := s.db.GetNutritionBetween(userID, start, end)
+			if err != nil {
+				return nil, err
+			}
+			spent, err := s.db.GetDollarsSpentBetween(userID, start, end)
+			if err != nil {
+				return nil, err
+			}
+			report.Days = append(report.Days, NutritionDay{
+				Date:   start.Format("2006-01-02"),
+				Totals: totals,
+				Spent:  roundCents(spent),
+			})
+		}
+	}
+
+	daily, err := s.GetNutritionGoals(userID)
+	if err != nil {
+		return nil, err
+	}
+	if daily != nil {
+		goals := scaleNutrition(*daily, float64(days))
+		report.Goals = &goals
+		report.Progress = &models.Nutrition{
+			Calories: percentOf(report.Totals.Calories, goals.Calories),
+			Protein:  percentOf(report.Totals.Protein, goals.Protein),
+			Carbs:    percentOf(report.Totals.Carbs, goals.Carbs),
+			Fat:      percentOf(report.Totals.Fat, goals.Fat),
+		}
+	}
+
+	return report, nil
+}
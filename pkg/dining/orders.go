@@ -0,0 +1,277 @@
+package dining
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	maxOrderLines     = 50
+	maxLineQuantity   = 20
+	maxDescriptionLen = 200
+)
+
+var (
+	ErrEmptyOrder      = errors.New("order has no items")
+	ErrInvalidQuantity = errors.New("invalid quantity")
+	ErrItemUnavailable = errors.New("menu item unavailable")
+	ErrMixedLocations  = errors.New("order items must come from a single location")
+	ErrInvalidDiscount = errors.New("invalid discount code")
+)
+
+type Discount struct {
+	ID          int64      `json:"id"`
+	Code        string     `json:"code"`
+	Description string     `json:"description"`
+	PercentOff  float64    `json:"percent_off"`
+	AmountOff   float64    `json:"amount_off"`
+	MinSubtotal float64    `json:"min_subtotal"`
+	LocationID  *int64     `json:"location_id,omitempty"`
+	Active      bool       `json:"active"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type OrderLine struct {
+	MenuItemID int64 `json:"menu_item_id"`
+	Quantity   int   `json:"quantity"`
+}
+
+type OrderRequest struct {
+	Items         []OrderLine `json:"items"`
+	DiscountCode  string      `json:"discount_code"`
+	ConfirmClosed bool        `json:"confirm_closed"`
+}
+
+type Quote struct {
+	Location     string                   `json:"location"`
+	Items        []models.TransactionItem `json:"items"`
+	Subtotal     float64                  `json:"subtotal"`
+	Discount     float64                  `json:"discount"`
+	DiscountCode string                   `json:"discount_code,omitempty"`
+	TaxRate      float64                  `json:"tax_rate"`
+	Tax          float64                  `json:"tax"`
+	Total        float64                  `json:"total"`
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func scaleNutrition(n models.Nutrition, factor float64) models.Nutrition {
+	return models.Nutrition{
+		Calories: n.Calories * factor,
+		Protein:  n.Protein * factor,
+		Carbs:    n.Carbs * factor,
+		Fat:      n.Fat * factor,
+	}
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func scanDiscount(row interface{ Scan(...interface{}) error }) (*Discount, error) {
+	var d Discount
+	var locationID sql.NullInt64
+	var expiresAt sql.NullTime
+	err := row.Scan(&d.ID, &d.Code, &d.Description, &d.PercentOff, &d.AmountOff, &d.MinSubtotal,
+		&locationID, &d.Active, &expiresAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if locationID.Valid {
+		d.LocationID = &locationID.Int64
+	}
+	if expiresAt.Valid {
+		d.ExpiresAt = &expiresAt.Time
+	}
+	return &d, nil
+}
+
+const discountSelect = `
+	SELECT id, code, description, percent_off, amount_off, min_subtotal, location_id, active, expires_at, created_at
+	FROM dining_discounts`
+
+func (s *Store) GetDiscountByCode(code string) (*Discount, error) {
+	d, err := scanDiscount(s.db.QueryRow(discountSelect+` WHERE code = ?`, normalizeCode(code)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting discount: %w", err)
+	}
+	return d, nil
+}
+
+func (s *Store) ListDiscounts() ([]Discount, error) {
+	rows, err := s.db.Query(discountSelect + ` ORDER BY code`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting discounts: %w", err)
+	}
+	defer rows.Close()
+
+	discounts := []Discount{}
+	for rows.Next() {
+		d, err := scanDiscount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning discount: %w", err)
+		}
+		discounts = append(discounts, *d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating discounts: %w", err)
+	}
+
+	return discounts, nil
+}
+
+func (s *Store) CreateDiscount(d *Discount) (*Discount, error) {
+	d.Code = normalizeCode(d.Code)
+	_, err := s.db.Exec(`
+		INSERT INTO dining_discounts (code, description, percent_off, amount_off, min_subtotal, location_id, active, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?)
+	`, d.Code, d.Description, d.PercentOff, d.AmountOff, d.MinSubtotal, d.LocationID, d.ExpiresAt, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating discount: %w", err)
+	}
+
+	return s.GetDiscountByCode(d.Code)
+}
+
+func (s *Store) PriceOrder(req OrderRequest, at time.Time) (*Quote, error) {
+	if len(req.Items) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	if len(req.Items) > maxOrderLines {
+		return nil, fmt.Errorf("%w: at most %d lines per order", ErrInvalidQuantity, maxOrderLines)
+	}
+
+	quantities := make(map[int64]int)
+	var order []int64
+	for _, line := range req.Items {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w for menu item %d", ErrInvalidQuantity, line.MenuItemID)
+		}
+		if _, ok := quantities[line.MenuItemID]; !ok {
+			order = append(order, line.MenuItemID)
+		}
+		quantities[line.MenuItemID] += line.Quantity
+		if quantities[line.MenuItemID] > maxLineQuantity {
+			return nil, fmt.Errorf("%w: at most %d of each item", ErrInvalidQuantity, maxLineQuantity)
+		}
+	}
+
+	quote := &Quote{TaxRate: s.TaxRate}
+	var locationID int64
+	lineSubtotals := make([]float64, len(order))
+	for i, id := range order {
+		menuItem, err := s.GetMenuItem(id)
+		if errors.Is(err, ErrNotFound) || (err == nil && !menuItem.Active) {
+			return nil, fmt.Errorf("%w: %d", ErrItemUnavailable, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if locationID == 0 {
+			locationID = menuItem.LocationID
+			quote.Location = menuItem.Location
+		} else if menuItem.LocationID != locationID {
+			return nil, ErrMixedLocations
+		}
+
+		menuItemID := menuItem.ID
+		lineSubtotals[i] = roundCents(menuItem.Price * float64(quantities[id]))
+		quote.Subtotal += lineSubtotals[i]
+		quote.Items = append(quote.Items, models.TransactionItem{
+			MenuItemID: &menuItemID,
+			Name:       menuItem.Name,
+			Quantity:   quantities[id],
+			UnitPrice:  menuItem.Price,
+			Nutrition:  scaleNutrition(menuItem.Nutrition, float64(quantities[id])),
+		})
+	}
+	quote.Subtotal = roundCents(quote.Subtotal)
+
+	if req.DiscountCode != "" {
+		discount, err := s.GetDiscountByCode(req.DiscountCode)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case discount == nil || !discount.Active:
+			return nil, ErrInvalidDiscount
+		case discount.ExpiresAt != nil && !at.Before(*discount.ExpiresAt):
+			return nil, fmt.Errorf("%w: expired", ErrInvalidDiscount)
+		case discount.LocationID != nil && *discount.LocationID != locationID:
+			return nil, fmt.Errorf("%w: not valid at %s", ErrInvalidDiscount, quote.Location)
+		case quote.Subtotal < discount.MinSubtotal:
+			return nil, fmt.Errorf("%w: requires a subtotal of at least $%.2f", ErrInvalidDiscount, discount.MinSubtotal)
+		}
+		quote.DiscountCode = discount.Code
+		quote.Discount = math.Min(quote.Subtotal, roundCents(quote.Subtotal*discount.PercentOff/100+discount.AmountOff))
+	}
+
+	remaining := quote.Discount
+	for i := range quote.Items {
+		lineDiscount := remaining
+		if i < len(quote.Items)-1 && quote.Subtotal > 0 {
+			lineDiscount = math.Min(remaining, roundCents(quote.Discount*lineSubtotals[i]/quote.Subtotal))
+		}
+		remaining = roundCents(remaining - lineDiscount)
+
+		item := &quote.Items[i]
+		item.Discount = lineDiscount
+		item.Tax = roundCents((lineSubtotals[i] - lineDiscount) * s.TaxRate)
+		item.Total = roundCents(lineSubtotals[i] - lineDiscount + item.Tax)
+		quote.Tax += item.Tax
+		quote.Total += item.Total
+	}
+	quote.Tax = roundCents(quote.Tax)
+	quote.Total = roundCents(quote.Total)
+
+	return quote, nil
+}
+
+func orderDescription(quote *Quote) string {
+	names := make([]string, len(quote.Items))
+	for i, item := range quote.Items {
+		if item.Quantity > 1 {
+			names[i] = fmt.Sprintf("%dx %s", item.Quantity, item.Name)
+		} else {
+			names[i] = item.Name
+		}
+	}
+
+	description := strings.Join(names, ", ")
+	if runes := []rune(description); len(runes) > maxDescriptionLen {
+		description = string(runes[:maxDescriptionLen-3]) + "..."
+	}
+	return description
+}
+
+func (s *Store) WithAudit(meta models.AuditMeta) *Store {
+	c := *s
+	c.db = s.db.WithAudit(meta)
+	return &c
+}
+
+func (s *Store) PlaceOrder(userID int64, quote *Quote) (*models.Transaction, error) {
+	tx := &models.Transaction{
+		UserID:          userID,
+		Amount:          quote.Total,
+		Location:        quote.Location,
+		Description:     orderDescription(quote),
+		TransactionDate: time.Now(),
+	}
+
+	return s.db.CreateItemizedTransaction(tx, quote.Items)
+}
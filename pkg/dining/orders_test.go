@@ -0,0 +1,233 @@
+package dining
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+type orderFixture struct {
+	db    *models.DB
+	store *Store
+	items map[string]int64
+}
+
+func newOrderFixture(t *testing.T) *orderFixture {
+	t.Helper()
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "dining.db"))
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	store.TaxRate = DefaultTaxRate
+
+	f := &orderFixture{db: db, store: store, items: map[string]int64{}}
+	kitchen, err := store.CreateLocation(&Location{Name: "Test Kitchen"})
+	if err != nil {
+		t.Fatalf("CreateLocation: %v", err)
+	}
+	for _, item := range []MenuItem{
+		{Name: "Apple", Price: 3.33, MealType: "lunch", Location: kitchen.Name},
+		{Name: "Bagel", Price: 1.99, MealType: "lunch", Location: kitchen.Name},
+		{Name: "Curry", Price: 10.00, MealType: "lunch", Location: kitchen.Name, Nutrition: models.Nutrition{Calories: 640}},
+		{Name: "Elsewhere", Price: 4.00, MealType: "lunch", Location: "Market Cafe"},
+	} {
+		created, err := store.CreateMenuItem(&item)
+		if err != nil {
+			t.Fatalf("CreateMenuItem %s: %v", item.Name, err)
+		}
+		f.items[item.Name] = created.ID
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	for _, d := range []Discount{
+		{Code: "TENOFF", PercentOff: 10},
+		{Code: "FIVEOFF", AmountOff: 5},
+		{Code: "TWENTYOFF", AmountOff: 20},
+		{Code: "BIGSPEND", PercentOff: 50, MinSubtotal: 25},
+		{Code: "EXPIRED", PercentOff: 50, ExpiresAt: &expired},
+		{Code: "KITCHEN", AmountOff: 1, LocationID: &kitchen.ID},
+	} {
+		if _, err := store.CreateDiscount(&d); err != nil {
+			t.Fatalf("CreateDiscount %s: %v", d.Code, err)
+		}
+	}
+	return f
+}
+
+func (f *orderFixture) order(code string, lines ...interface{}) OrderRequest {
+	req := OrderRequest{DiscountCode: code}
+	for i := 0; i < len(lines); i += 2 {
+		req.Items = append(req.Items, OrderLine{MenuItemID: f.items[lines[i].(string)], Quantity: lines[i+1].(int)})
+	}
+	return req
+}
+
+type quoteLine struct {
+	quantity int
+	discount float64
+	tax      float64
+	total    float64
+}
+
+func TestPriceOrder(t *testing.T) {
+	f := newOrderFixture(t)
+
+	tests := []struct {
+		name     string
+		req      OrderRequest
+		subtotal float64
+		discount float64
+		tax      float64
+		total    float64
+		lines    []quoteLine
+	}{
+		{
+			name:     "no discount",
+			req:      f.order("", "Apple", 3, "Bagel", 1),
+			subtotal: 11.98, tax: 1.03, total: 13.01,
+			lines: []quoteLine{{3, 0, 0.86, 10.85}, {1, 0, 0.17, 2.16}},
+		},
+		{
+			name:     "percent off spread across lines",
+			req:      f.order("tenoff", "Apple", 3, "Bagel", 1),
+			subtotal: 11.98, discount: 1.20, tax: 0.93, total: 11.71,
+			lines: []quoteLine{{3, 1.00, 0.78, 9.77}, {1, 0.20, 0.15, 1.94}},
+		},
+		{
+			name:     "amount off remainder lands on last line",
+			req:      f.order("FIVEOFF", "Apple", 1, "Bagel", 1, "Curry", 1),
+			subtotal: 15.32, discount: 5.00, tax: 0.89, total: 11.21,
+			lines: []quoteLine{{1, 1.09, 0.19, 2.43}, {1, 0.65, 0.12, 1.46}, {1, 3.26, 0.58, 7.32}},
+		},
+		{
+			name:     "discount capped at subtotal",
+			req:      f.order("TWENTYOFF", "Bagel", 2),
+			subtotal: 3.98, discount: 3.98, tax: 0, total: 0,
+			lines: []quoteLine{{2, 3.98, 0, 0}},
+		},
+		{
+			name:     "repeated items merge into one line",
+			req:      f.order("", "Bagel", 1, "Bagel", 2),
+			subtotal: 5.97, tax: 0.51, total: 6.48,
+			lines: []quoteLine{{3, 0, 0.51, 6.48}},
+		},
+		{
+			name:     "location discount",
+			req:      f.order("KITCHEN", "Curry", 1),
+			subtotal: 10.00, discount: 1.00, tax: 0.78, total: 9.78,
+			lines: []quoteLine{{1, 1.00, 0.78, 9.78}},
+		},
+	}
+
+	for _, tt := range tests {
+		quote, err := f.store.PriceOrder(tt.req, time.Now())
+		if err != nil {
+			t.Fatalf("%s: PriceOrder: %v", tt.name, err)
+		}
+		if quote.Subtotal != tt.subtotal || quote.Discount != tt.discount || quote.Tax != tt.tax || quote.Total != tt.total {
+			t.Errorf("%s: subtotal %.2f discount %.2f tax %.2f total %.2f, want %.2f %.2f %.2f %.2f", tt.name,
+				quote.Subtotal, quote.Discount, quote.Tax, quote.Total, tt.subtotal, tt.discount, tt.tax, tt.total)
+		}
+		if len(quote.Items) != len(tt.lines) {
+			t.Fatalf("%s: %d lines, want %d", tt.name, len(quote.Items), len(tt.lines))
+		}
+		for i, want := range tt.lines {
+			got := quote.Items[i]
+			if got.Quantity != want.quantity || got.Discount != want.discount || got.Tax != want.tax || got.Total != want.total {
+				t.Errorf("%s: line %d = qty %d discount %.2f tax %.2f total %.2f, want %+v", tt.name, i,
+					got.Quantity, got.Discount, got.Tax, got.Total, want)
+			}
+		}
+	}
+}
+
+func TestPriceOrderRejects(t *testing.T) {
+	f := newOrderFixture(t)
+
+	tests := []struct {
+		name string
+		req  OrderRequest
+		want error
+	}{
+		{"empty order", f.order(""), ErrEmptyOrder},
+		{"zero quantity", f.order("", "Apple", 0), ErrInvalidQuantity},
+		{"too many of one item", f.order("", "Apple", 15, "Apple", 6), ErrInvalidQuantity},
+		{"mixed locations", f.order("", "Apple", 1, "Elsewhere", 1), ErrMixedLocations},
+		{"unknown code", f.order("NOPE", "Apple", 1), ErrInvalidDiscount},
+		{"expired code", f.order("EXPIRED", "Apple", 1), ErrInvalidDiscount},
+		{"below minimum subtotal", f.order("BIGSPEND", "Curry", 2), ErrInvalidDiscount},
+		{"code for another location", f.order("KITCHEN", "Elsewhere", 1), ErrInvalidDiscount},
+	}
+
+	for _, tt := range tests {
+		if _, err := f.store.PriceOrder(tt.req, time.Now()); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPlaceOrderRecordsItemizedTransaction(t *testing.T) {
+	f := newOrderFixture(t)
+	user, err := f.db.CreateUser("62000001", "Order User", "62000001@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	before, err := f.db.GetUserBalance(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserBalance: %v", err)
+	}
+
+	quote, err := f.store.PriceOrder(f.order("FIVEOFF", "Apple", 1, "Bagel", 1, "Curry", 2), time.Now())
+	if err != nil {
+		t.Fatalf("PriceOrder: %v", err)
+	}
+	tx, err := f.store.PlaceOrder(user.ID, quote)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if tx.Amount != quote.Total || tx.Location != "Test Kitchen" || tx.Description != "Apple, Bagel, 2x Curry" {
+		t.Fatalf("transaction = %+v, want total %.2f", tx, quote.Total)
+	}
+
+	items, err := f.db.GetTransactionItems([]int64{tx.ID})
+	if err != nil {
+		t.Fatalf("GetTransactionItems: %v", err)
+	}
+	var discount, tax, total float64
+	for _, item := range items[tx.ID] {
+		discount += item.Discount
+		tax += item.Tax
+		total += item.Total
+	}
+	if cents(discount) != cents(quote.Discount) || cents(tax) != cents(quote.Tax) || cents(total) != cents(quote.Total) {
+		t.Errorf("stored items sum to discount %.2f tax %.2f total %.2f, want %.2f %.2f %.2f",
+			discount, tax, total, quote.Discount, quote.Tax, quote.Total)
+	}
+	if got := items[tx.ID][2].Nutrition.Calories; got != 1280 {
+		t.Errorf("curry calories = %.0f, want 1280", got)
+	}
+
+	after, err := f.db.GetUserBalance(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserBalance: %v", err)
+	}
+	if cents(before.CurrentBalance-after.CurrentBalance) != cents(quote.Total) {
+		t.Errorf("balance dropped by %.2f, want %.2f", before.CurrentBalance-after.CurrentBalance, quote.Total)
+	}
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
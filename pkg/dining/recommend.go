@@ -0,0 +1,269 @@
+package dining
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	historyWindow          = 90 * 24 * time.Hour
+	defaultRecommendations = 10
+	maxCombos              = 5
+)
+
+type Allowance struct {
+	WeeklyBudget   float64 `json:"weekly_budget"`
+	SpentThisWeek  float64 `json:"spent_this_week"`
+	SpentToday     float64 `json:"spent_today"`
+	DaysLeft       int     `json:"days_left"`
+	CurrentBalance float64 `json:"current_balance"`
+	TodayRemaining float64 `json:"today_remaining"`
+}
+
+type Recommendation struct {
+	Item           MenuItem `json:"item"`
+	PriceWithTax   float64  `json:"price_with_tax"`
+	TimesPurchased int      `json:"times_purchased"`
+}
+
+type Combo struct {
+	Items          []MenuItem `json:"items"`
+	Location       string     `json:"location"`
+	Total          float64    `json:"total"`
+	TimesPurchased int        `json:"times_purchased"`
+}
+
+type Recommendations struct {
+	Allowance           Allowance        `json:"allowance"`
+	MealType            string           `json:"meal_type,omitempty"`
+	DietaryRestrictions []string         `json:"dietary_restrictions"`
+	Items               []Recommendation `json:"items"`
+	Combos              []Combo          `json:"combos"`
+}
+
+type RecommendOptions struct {
+	MealType            string
+	DietaryRestrictions []string
+	Limit               int
+	Now                 time.Time
+}
+
+func (s *Store) GetDietaryPreferences(userID int64) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT restriction FROM user_dietary_preferences WHERE user_id = ? ORDER BY restriction
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting dietary preferences: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := []string{}
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return nil, fmt.Errorf("error scanning dietary preference: %w", err)
+		}
+		preferences = append(preferences, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dietary preferences: %w", err)
+	}
+
+	return preferences, nil
+}
+
+func (s *Store) SetDietaryPreferences(userID int64, restrictions []string) ([]string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_dietary_preferences WHERE user_id = ?`, userID); err != nil {
+		return nil, fmt.Errorf("error clearing dietary preferences: %w", err)
+	}
+
+	restrictions = normalizeRestrictions(restrictions)
+	for _, r := range restrictions {
+		_, err := tx.Exec(`INSERT INTO user_dietary_preferences (user_id, restriction) VALUES (?, ?)`, userID, r)
+		if err != nil {
+			return nil, fmt.Errorf("error saving dietary preference: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return restrictions, nil
+}
+
+func (s *Store) CurrentMealPeriod(at time.Time) (*MealPeriod, error) {
+	periods, err := s.ListMealPeriods()
+	if err != nil {
+		return nil, err
+	}
+
+	clock := at.In(time.Local).Format("15:04")
+	for _, p := range periods {
+		if clock < p.EndsAt {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Store) TodayAllowance(userID int64, now time.Time) (*Allowance, error) {
+	settings, err := s.db.GetBudgetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.db.GetUserBalance(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	weekStart, weekEnd := models.WeekBounds(now)
+	dayStart, dayEnd := models.DayBounds(now)
+
+	spentBeforeToday, err := s.db.GetDollarsSpentBetween(userID, weekStart, dayStart)
+	if err != nil {
+		return nil, err
+	}
+
+	spentToday, err := s.db.GetDollarsSpentBetween(userID, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Allowance{
+		WeeklyBudget:   settings.WeeklyBudget,
+		SpentThisWeek:  roundCents(spentBeforeToday + spentToday),
+		SpentToday:     roundCents(spentToday),
+		DaysLeft:       int(math.Round(weekEnd.Sub(dayStart).Hours() / 24)),
+		CurrentBalance: balance.CurrentBalance,
+	}
+
+	remaining := balance.CurrentBalance
+	if settings.WeeklyBudget > 0 {
+		daily := math.Max(0, settings.WeeklyBudget-spentBeforeToday) / float64(a.DaysLeft)
+		remaining = math.Min(remaining, daily-spentToday)
+	}
+	a.TodayRemaining = roundCents(math.Max(0, remaining))
+
+	return a, nil
+}
+
+func (s *Store) Recommend(userID int64, opts RecommendOptions) (*Recommendations, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultRecommendations
+	}
+
+	allowance, err := s.TodayAllowance(userID, opts.Now)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating allowance: %w", err)
+	}
+
+	restrictions := opts.DietaryRestrictions
+	if restrictions == nil {
+		if restrictions, err = s.GetDietaryPreferences(userID); err != nil {
+			return nil, err
+		}
+	}
+	restrictions = normalizeRestrictions(restrictions)
+
+	mealType := opts.MealType
+	if mealType == "" {
+		period, err := s.CurrentMealPeriod(opts.Now)
+		if err != nil {
+			return nil, err
+		}
+		if period != nil {
+			mealType = period.Name
+		}
+	}
+
+	menu, err := s.ListMenu(MenuFilter{MealType: mealType, DietaryRestrictions: restrictions})
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.db.GetMenuItemPurchaseCounts(userID, opts.Now.Add(-historyWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Recommendations{
+		Allowance:           *allowance,
+		MealType:            mealType,
+		DietaryRestrictions: restrictions,
+		Items:               []Recommendation{},
+		Combos:              []Combo{},
+	}
+
+	var fits []Recommendation
+	for _, item := range menu {
+		rec := Recommendation{
+			Item:           item,
+			PriceWithTax:   roundCents(item.Price * (1 + s.TaxRate)),
+			TimesPurchased: counts[item.ID],
+		}
+		if rec.PriceWithTax <= allowance.TodayRemaining {
+			fits = append(fits, rec)
+		}
+	}
+
+	sort.SliceStable(fits, func(i, j int) bool {
+		if fits[i].TimesPurchased != fits[j].TimesPurchased {
+			return fits[i].TimesPurchased > fits[j].TimesPurchased
+		}
+		return fits[i].PriceWithTax > fits[j].PriceWithTax
+	})
+
+	for i := 0; i < len(fits); i++ {
+		for j := i + 1; j < len(fits); j++ {
+			a, b := fits[i], fits[j]
+			if a.Item.LocationID != b.Item.LocationID {
+				continue
+			}
+			total := roundCents(a.PriceWithTax + b.PriceWithTax)
+			if total > allowance.TodayRemaining {
+				continue
+			}
+			result.Combos = append(result.Combos, Combo{
+				Items:          []MenuItem{a.Item, b.Item},
+				Location:       a.Item.Location,
+				Total:          total,
+				TimesPurchased: a.TimesPurchased + b.TimesPurchased,
+			})
+		}
+	}
+
+	sort.SliceStable(result.Combos, func(i, j int) bool {
+		if result.Combos[i].TimesPurchased != result.Combos[j].TimesPurchased {
+			return result.Combos[i].TimesPurchased > result.Combos[j].TimesPurchased
+		}
+		return result.Combos[i].Total > result.Combos[j].Total
+	})
+
+	if len(fits) > opts.Limit {
+		fits = fits[:opts.Limit]
+	}
+	if len(result.Combos) > maxCombos {
+		result.Combos = result.Combos[:maxCombos]
+	}
+	if fits != nil {
+		result.Items = fits
+	}
+
+	return result, nil
+}
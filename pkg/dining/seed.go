@@ -0,0 +1,147 @@
+package dining
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+var seedMealPeriods = []MealPeriod{
+	{Name: "breakfast", StartsAt: "07:00", EndsAt: "10:30"},
+	{Name: "lunch", StartsAt: "11:00", EndsAt: "14:30"},
+	{Name: "dinner", StartsAt: "17:00", EndsAt: "20:30"},
+}
+
+var seedLocations = []Location{
+	{Name: "Market Cafe", Description: "Main dining hall in the University Center", Icon: "fa-utensils"},
+	{Name: "Crossroads Cafe", Description: "Grab-and-go cafe in Harney Science Center", Icon: "fa-coffee"},
+	{Name: "Lone Mountain Cafe", Description: "Dining on the Lone Mountain campus", Icon: "fa-mountain"},
+}
+
+var seedMenuItems = []MenuItem{
+	{Name: "Avocado Toast", Price: 8.99, MealType: "breakfast", Location: "Market Cafe", DietaryRestrictions: []string{"vegetarian", "dairy-free"}, Nutrition: models.Nutrition{Calories: 420, Protein: 12, Carbs: 45, Fat: 22}},
+	{Name: "Belgian Waffle", Price: 7.50, MealType: "breakfast", Location: "Market Cafe", DietaryRestrictions: []string{"vegetarian"}, Nutrition: models.Nutrition{Calories: 510, Protein: 9, Carbs: 68, Fat: 22}},
+	{Name: "Breakfast Burrito", Price: 9.25, MealType: "breakfast", Location: "Crossroads Cafe", Nutrition: models.Nutrition{Calories: 690, Protein: 32, Carbs: 58, Fat: 36}},
+	{Name: "Greek Yogurt Parfait", Price: 5.99, MealType: "breakfast", Location: "Crossroads Cafe", DietaryRestrictions: []string{"vegetarian", "gluten-free"}, Nutrition: models.Nutrition{Calories: 290, Protein: 18, Carbs: 42, Fat: 6}},
+	{Name: "Grilled Chicken Sandwich", Price: 10.50, MealType: "lunch", Location: "Market Cafe", Nutrition: models.Nutrition{Calories: 560, Protein: 42, Carbs: 48, Fat: 20}},
+	{Name: "Quinoa Bowl", Price: 11.25, MealType: "lunch", Location: "Market Cafe", DietaryRestrictions: []string{"vegetarian", "gluten-free", "dairy-free"}, Nutrition: models.Nutrition{Calories: 480, Protein: 16, Carbs: 70, Fat: 15}},
+	{Name: "Caprese Panini", Price: 9.99, MealType: "lunch", Location: "Crossroads Cafe", DietaryRestrictions: []string{"vegetarian"}, Nutrition: models.Nutrition{Calories: 610, Protein: 26, Carbs: 55, Fat: 30}},
+	{Name: "Caesar Salad", Price: 8.75, MealType: "lunch", Location: "Crossroads Cafe", Nutrition: models.Nutrition{Calories: 440, Protein: 14, Carbs: 20, Fat: 34}},
+	{Name: "Mushroom Risotto", Price: 13.50, MealType: "dinner", Location: "Lone Mountain Cafe", DietaryRestrictions: []string{"vegetarian", "gluten-free"}, Nutrition: models.Nutrition{Calories: 620, Protein: 14, Carbs: 82, Fat: 24}},
+	{Name: "Grilled Salmon", Price: 16.99, MealType: "dinner", Location: "Lone Mountain Cafe", DietaryRestrictions: []string{"gluten-free", "dairy-free"}, Nutrition: models.Nutrition{Calories: 540, Protein: 46, Carbs: 12, Fat: 32}},
+	{Name: "Margherita Pizza", Price: 12.50, MealType: "dinner", Location: "Market Cafe", DietaryRestrictions: []string{"vegetarian"}, Nutrition: models.Nutrition{Calories: 780, Protein: 32, Carbs: 92, Fat: 30}},
+	{Name: "Beef Stir-Fry", Price: 14.25, MealType: "dinner", Location: "Market Cafe", DietaryRestrictions: []string{"dairy-free"}, Nutrition: models.Nutrition{Calories: 650, Protein: 38, Carbs: 60, Fat: 26}},
+	{Name: "Vegan Burger", Price: 11.99, MealType: "lunch", Location: "Lone Mountain Cafe", DietaryRestrictions: []string{"vegan", "vegetarian", "dairy-free"}, Nutrition: models.Nutrition{Calories: 590, Protein: 24, Carbs: 64, Fat: 26}},
+	{Name: "Fruit Smoothie Bowl", Price: 7.99, MealType: "breakfast", Location: "Lone Mountain Cafe", DietaryRestrictions: []string{"vegan", "vegetarian", "gluten-free", "dairy-free"}, Nutrition: models.Nutrition{Calories: 360, Protein: 8, Carbs: 72, Fat: 7}},
+	{Name: "Pasta Primavera", Price: 13.99, MealType: "dinner", Location: "Lone Mountain Cafe", DietaryRestrictions: []string{"vegetarian"}, Nutrition: models.Nutrition{Calories: 640, Protein: 20, Carbs: 96, Fat: 18}},
+}
+
+func weekdayHours(weekday, weekend [2]string) []Hours {
+	var hours []Hours
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		window := weekday
+		if d == time.Saturday || d == time.Sunday {
+			window = weekend
+		}
+		if window[0] == "" {
+			continue
+		}
+		hours = append(hours, Hours{Weekday: d.String(), OpensAt: window[0], ClosesAt: window[1]})
+	}
+	return hours
+}
+
+var seedSchedules = []Schedule{
+	{Location: "Market Cafe", Hours: weekdayHours([2]string{"07:00", "21:00"}, [2]string{"09:00", "20:00"})},
+	{Location: "Market Cafe", Term: models.TermKindSummer, Hours: weekdayHours([2]string{"08:00", "15:00"}, [2]string{})},
+	{Location: "Crossroads Cafe", Hours: weekdayHours([2]string{"07:30", "16:00"}, [2]string{})},
+	{Location: "Lone Mountain Cafe", Hours: weekdayHours([2]string{"07:00", "20:30"}, [2]string{"10:00", "19:00"})},
+}
+
+func (s *Store) seedHours() error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM location_hours`).Scan(&count); err != nil {
+		return fmt.Errorf("error counting hours: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for i := range seedSchedules {
+		location, err := s.GetLocationByName(seedSchedules[i].Location)
+		if err != nil {
+			return err
+		}
+		if location == nil {
+			continue
+		}
+		if err := s.SetSchedule(&seedSchedules[i]); err != nil {
+			return fmt.Errorf("error seeding hours for %s: %w", seedSchedules[i].Location, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) seed() error {
+	for _, p := range seedMealPeriods {
+		_, err := s.db.Exec(`
+			INSERT OR IGNORE INTO meal_periods (name, starts_at, ends_at) VALUES (?, ?, ?)
+		`, p.Name, p.StartsAt, p.EndsAt)
+		if err != nil {
+			return fmt.Errorf("error seeding meal period %s: %w", p.Name, err)
+		}
+	}
+
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM dining_locations`).Scan(&count); err != nil {
+		return fmt.Errorf("error counting locations: %w", err)
+	}
+	if count > 0 {
+		if err := s.backfillNutrition(); err != nil {
+			return err
+		}
+		return s.seedHours()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, l := range seedLocations {
+		_, err := tx.Exec(`
+			INSERT INTO dining_locations (name, description, icon, created_at) VALUES (?, ?, ?, ?)
+		`, l.Name, l.Description, l.Icon, time.Now())
+		if err != nil {
+			return fmt.Errorf("error seeding location %s: %w", l.Name, err)
+		}
+	}
+
+	for i := range seedMenuItems {
+		if _, _, err := upsertMenuItem(tx, &seedMenuItems[i], false); err != nil {
+			return fmt.Errorf("error seeding menu item %s: %w", seedMenuItems[i].Name, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return s.seedHours()
+}
+
+func (s *Store) backfillNutrition() error {
+	for _, item := range seedMenuItems {
+		_, err := s.db.Exec(`
+			UPDATE menu_items
+			SET calories = ?, protein_g = ?, carbs_g = ?, fat_g = ?
+			WHERE name = ? AND calories = 0 AND protein_g = 0 AND carbs_g = 0 AND fat_g = 0
+		`, item.Nutrition.Calories, item.Nutrition.Protein, item.Nutrition.Carbs, item.Nutrition.Fat, item.Name)
+		if err != nil {
+			return fmt.Errorf("error backfilling nutrition for %s: %w", item.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, account Account) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Begin() error {
+	return c.w.Write([]string{"id", "date", "asset", "amount", "location", "description"})
+}
+
+func (c *csvWriter) Write(tx *models.Transaction) error {
+	return c.w.Write([]string{
+		strconv.FormatInt(tx.ID, 10),
+		tx.TransactionDate.Format(time.RFC3339),
+		tx.Asset,
+		formatAmount(tx.Amount),
+		tx.Location,
+		tx.Description,
+	})
+}
+
+func (c *csvWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
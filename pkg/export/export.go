@@ -0,0 +1,48 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+type Account struct {
+	StudentID string
+	Name      string
+	Balance   float64
+	From      time.Time
+	To        time.Time
+}
+
+type Writer interface {
+	Begin() error
+	Write(tx *models.Transaction) error
+	End() error
+}
+
+type Format struct {
+	ContentType string
+	Extension   string
+	New         func(w io.Writer, account Account) Writer
+}
+
+var formats = map[string]Format{
+	"csv":  {ContentType: "text/csv; charset=utf-8", Extension: "csv", New: newCSVWriter},
+	"json": {ContentType: "application/json", Extension: "json", New: newJSONWriter},
+	"ofx":  {ContentType: "application/x-ofx; charset=utf-8", Extension: "ofx", New: newOFXWriter},
+}
+
+func Lookup(name string) (Format, error) {
+	f, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unsupported export format %q", name)
+	}
+	return f, nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+type jsonWriter struct {
+	w     io.Writer
+	first bool
+}
+
+type jsonTransaction struct {
+	ID              int64       `json:"id"`
+	Asset           string      `json:"asset"`
+	Amount          json.Number `json:"amount"`
+	Location        string      `json:"location"`
+	Description     string      `json:"description"`
+	TransactionDate time.Time   `json:"transaction_date"`
+}
+
+func newJSONWriter(w io.Writer, account Account) Writer {
+	return &jsonWriter{w: w, first: true}
+}
+
+func (j *jsonWriter) Begin() error {
+	_, err := io.WriteString(j.w, `{"transactions":[`)
+	return err
+}
+
+func (j *jsonWriter) Write(tx *models.Transaction) error {
+	data, err := json.Marshal(jsonTransaction{
+		ID:              tx.ID,
+		Asset:           tx.Asset,
+		Amount:          json.Number(formatAmount(tx.Amount)),
+		Location:        tx.Location,
+		Description:     tx.Description,
+		TransactionDate: tx.TransactionDate,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !j.first {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.first = false
+
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) End() error {
+	_, err := io.WriteString(j.w, "]}\n")
+	return err
+}
@@ -0,0 +1,134 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const ofxTimeFormat = "20060102150405"
+
+var ofxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+type ofxWriter struct {
+	w       io.Writer
+	account Account
+}
+
+func newOFXWriter(w io.Writer, account Account) Writer {
+	return &ofxWriter{w: w, account: account}
+}
+
+func (o *ofxWriter) Begin() error {
+	now := time.Now().UTC().Format(ofxTimeFormat)
+	from := o.account.From
+	if from.IsZero() {
+		from = time.Unix(0, 0)
+	}
+	to := o.account.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	_, err := fmt.Fprintf(o.w, `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:UTF-8
+CHARSET:NONE
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS>
+<CODE>0
+<SEVERITY>INFO
+</STATUS>
+<DTSERVER>%s
+<LANGUAGE>ENG
+</SONRS>
+</SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STATUS>
+<CODE>0
+<SEVERITY>INFO
+</STATUS>
+<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM>
+<BANKID>FLEXIBUDGET
+<ACCTID>%s
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>%s
+<DTEND>%s
+`, now, ofxEscaper.Replace(o.account.StudentID), from.UTC().Format(ofxTimeFormat), to.UTC().Format(ofxTimeFormat))
+	return err
+}
+
+func (o *ofxWriter) Write(tx *models.Transaction) error {
+	if tx.Asset != models.AssetDollars {
+		return nil
+	}
+
+	trnType := "DEBIT"
+	if tx.Amount < 0 {
+		trnType = "CREDIT"
+	}
+
+	_, err := fmt.Fprintf(o.w, `<STMTTRN>
+<TRNTYPE>%s
+<DTPOSTED>%s
+<TRNAMT>%s
+<FITID>%d
+<NAME>%s
+<MEMO>%s
+</STMTTRN>
+`, trnType, tx.TransactionDate.UTC().Format(ofxTimeFormat), formatAmount(-tx.Amount), tx.ID,
+		ofxEscaper.Replace(truncateOFX(tx.Location, 32)), ofxEscaper.Replace(truncateOFX(tx.Description, 255)))
+	return err
+}
+
+func (o *ofxWriter) End() error {
+	if _, err := io.WriteString(o.w, "</BANKTRANLIST>\n"); err != nil {
+		return err
+	}
+
+	asOf := o.account.To
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	_, err := fmt.Fprintf(o.w, `<LEDGERBAL>
+<BALAMT>%s
+<DTASOF>%s
+</LEDGERBAL>
+`, formatAmount(o.account.Balance), asOf.UTC().Format(ofxTimeFormat))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(o.w, `</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`)
+	return err
+}
+
+func truncateOFX(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,330 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+)
+
+var ErrInvalidCSV = errors.New("invalid csv")
+
+var defaultDateFormats = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"01/02/2006",
+	"01/02/2006 15:04",
+	"1/2/2006",
+	"1/2/2006 15:04",
+}
+
+type Mapping struct {
+	DateColumn        string
+	AmountColumn      string
+	LocationColumn    string
+	DescriptionColumn string
+	DateFormat        string
+	Delimiter         rune
+	DebitsNegative    bool
+}
+
+func DefaultMapping() Mapping {
+	return Mapping{
+		DateColumn:        "date",
+		AmountColumn:      "amount",
+		LocationColumn:    "location",
+		DescriptionColumn: "description",
+		Delimiter:         ',',
+	}
+}
+
+type Row struct {
+	Line        int       `json:"line"`
+	Status      string    `json:"status"`
+	Reason      string    `json:"reason,omitempty"`
+	Date        time.Time `json:"date"`
+	Amount      float64   `json:"amount"`
+	Location    string    `json:"location"`
+	Description string    `json:"description"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+}
+
+type Report struct {
+	DryRun   bool    `json:"dry_run"`
+	Total    int     `json:"total_rows"`
+	Accepted int     `json:"accepted"`
+	Rejected int     `json:"rejected"`
+	Applied  bool    `json:"applied"`
+	Balance  float64 `json:"balance"`
+	Rows     []Row   `json:"rows"`
+}
+
+func Fingerprint(date time.Time, amount float64, location string) string {
+	return models.TransactionFingerprint(date, amount, location)
+}
+
+func Parse(r io.Reader, m Mapping) ([]Row, error) {
+	reader := csv.NewReader(r)
+	if m.Delimiter != 0 {
+		reader.Comma = m.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("csv is empty")
+		}
+		return nil, fmt.Errorf("error reading csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	index := func(name string, required bool) (int, error) {
+		if name == "" {
+			if required {
+				return -1, fmt.Errorf("column mapping is missing")
+			}
+			return -1, nil
+		}
+		i, ok := columns[strings.ToLower(name)]
+		if !ok {
+			if required {
+				return -1, fmt.Errorf("column %q not found in csv header", name)
+			}
+			return -1, nil
+		}
+		return i, nil
+	}
+
+	dateCol, err := index(m.DateColumn, true)
+	if err != nil {
+		return nil, err
+	}
+	amountCol, err := index(m.AmountColumn, true)
+	if err != nil {
+		return nil, err
+	}
+	locationCol, err := index(m.LocationColumn, true)
+	if err != nil {
+		return nil, err
+	}
+	descCol, err := index(m.DescriptionColumn, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+
+		if err != nil {
+			row.Status = StatusRejected
+			row.Reason = err.Error()
+			rows = append(rows, row)
+			continue
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row.Location = field(locationCol)
+		row.Description = field(descCol)
+
+		if row.Date, err = parseDate(field(dateCol), m.DateFormat); err != nil {
+			row.Status = StatusRejected
+			row.Reason = err.Error()
+		} else if row.Amount, err = parseAmount(field(amountCol), m.DebitsNegative); err != nil {
+			row.Status = StatusRejected
+			row.Reason = err.Error()
+		} else if row.Location == "" {
+			row.Status = StatusRejected
+			row.Reason = "location is required"
+		} else {
+			row.Fingerprint = Fingerprint(row.Date, row.Amount, row.Location)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseDate(value, format string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("date is required")
+	}
+
+	formats := defaultDateFormats
+	if format != "" {
+		formats = []string{format}
+	}
+
+	for _, f := range formats {
+		if t, err := time.ParseInLocation(f, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", value)
+}
+
+func parseAmount(value string, debitsNegative bool) (float64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("amount is required")
+	}
+
+	cleaned := strings.NewReplacer("$", "", ",", "", " ", "").Replace(value)
+	negative := false
+	if strings.HasPrefix(cleaned, "(") && strings.HasSuffix(cleaned, ")") {
+		cleaned = strings.Trim(cleaned, "()")
+		negative = true
+	}
+
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	if debitsNegative {
+		amount = -amount
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount must be positive; refunds and credits are not accepted")
+	}
+
+	return amount, nil
+}
+
+func Run(db *models.DB, userID int64, r io.Reader, m Mapping, dryRun bool) (*Report, error) {
+	rows, err := Parse(r, m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+
+	report := &Report{DryRun: dryRun, Total: len(rows), Rows: rows}
+
+	var start, end time.Time
+	for _, row := range rows {
+		if row.Fingerprint == "" {
+			continue
+		}
+		if start.IsZero() || row.Date.Before(start) {
+			start = row.Date
+		}
+		if end.IsZero() || row.Date.After(end) {
+			end = row.Date
+		}
+	}
+
+	existing := make(map[string]int)
+	if !start.IsZero() {
+		dayStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		dayEnd := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location()).AddDate(0, 0, 1)
+
+		transactions, err := db.GetTransactionsBetween(userID, dayStart, dayEnd)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range transactions {
+			if tx.Asset != models.AssetDollars {
+				continue
+			}
+			existing[Fingerprint(tx.TransactionDate, tx.Amount, tx.Location)]++
+		}
+	}
+
+	var accepted []models.Transaction
+	var acceptedRows []int
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Status == StatusRejected {
+			report.Rejected++
+			continue
+		}
+
+		if err := db.CheckTermOpen(row.Date); err != nil {
+			if !errors.Is(err, models.ErrTermClosed) {
+				return nil, err
+			}
+			row.Status = StatusRejected
+			row.Reason = "date falls in a closed term"
+			report.Rejected++
+			continue
+		}
+
+		if existing[row.Fingerprint] > 0 {
+			existing[row.Fingerprint]--
+			row.Status = StatusRejected
+			row.Reason = "duplicate of an existing transaction"
+			report.Rejected++
+			continue
+		}
+
+		row.Status = StatusAccepted
+		report.Accepted++
+		acceptedRows = append(acceptedRows, i)
+		accepted = append(accepted, models.Transaction{
+			UserID:          userID,
+			Amount:          row.Amount,
+			Location:        row.Location,
+			Description:     row.Description,
+			TransactionDate: row.Date,
+		})
+	}
+
+	if dryRun || len(accepted) == 0 {
+		balance, err := db.GetUserBalance(userID)
+		if err != nil {
+			return nil, err
+		}
+		report.Balance = balance.CurrentBalance
+		for _, tx := range accepted {
+			report.Balance -= tx.Amount
+		}
+		return report, nil
+	}
+
+	var duplicates []int
+	report.Balance, duplicates, err = db.ImportTransactions(userID, accepted)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range duplicates {
+		row := &report.Rows[acceptedRows[i]]
+		row.Status = StatusRejected
+		row.Reason = "duplicate of an existing transaction"
+		report.Accepted--
+		report.Rejected++
+	}
+	report.Applied = true
+
+	return report, nil
+}
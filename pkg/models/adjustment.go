@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	AdjustmentPlanChange         = "plan_change"
+	AdjustmentCloseoutForfeit    = "closeout_forfeit"
+	AdjustmentCloseoutConversion = "closeout_conversion"
+	AdjustmentManual             = "manual"
+)
+
+func (db *DB) applyAdjustment(dbTx *sql.Tx, adj *BalanceAdjustment) error {
+	if adj.CreatedAt.IsZero() {
+		adj.CreatedAt = time.Now()
+	}
+
+	before, err := balanceState(dbTx, adj.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = dbTx.QueryRow(`
+		INSERT INTO balance_adjustments (user_id, kind, amount, reason, term_id, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, adj.UserID, adj.Kind, adj.Amount, adj.Reason, adj.TermID, adj.CreatedBy, adj.CreatedAt).Scan(&adj.ID)
+	if err != nil {
+		return fmt.Errorf("error recording adjustment: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		UPDATE balances
+		SET current_balance = current_balance + ?, updated_at = ?
+		WHERE user_id = ?
+	`, adj.Amount, time.Now(), adj.UserID)
+	if err != nil {
+		return fmt.Errorf("error updating balance: %w", err)
+	}
+
+	after, err := balanceState(dbTx, adj.UserID)
+	if err != nil {
+		return err
+	}
+	after["adjustment"] = adj
+	return db.recordChange(dbTx, "adjustment.create", adj.UserID, before, after)
+}
+
+func (db *DB) CreateManualAdjustment(userID int64, amount float64, reason string, createdBy int64) (*BalanceAdjustment, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	adj := &BalanceAdjustment{
+		UserID:    userID,
+		Kind:      AdjustmentManual,
+		Amount:    roundCents(amount),
+		Reason:    reason,
+		CreatedBy: &createdBy,
+	}
+	if err := db.applyAdjustment(dbTx, adj); err != nil {
+		return nil, err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return adj, nil
+}
+
+func (db *DB) GetAdjustmentsBetween(userID int64, start, end time.Time) ([]BalanceAdjustment, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, kind, amount, reason, term_id, created_by, created_at
+		FROM balance_adjustments
+		WHERE user_id = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC, id ASC
+	`, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	var adjustments []BalanceAdjustment
+	for rows.Next() {
+		var adj BalanceAdjustment
+		var termID, createdBy sql.NullInt64
+		err := rows.Scan(&adj.ID, &adj.UserID, &adj.Kind, &adj.Amount, &adj.Reason, &termID, &createdBy, &adj.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning adjustment: %w", err)
+		}
+		if termID.Valid {
+			adj.TermID = &termID.Int64
+		}
+		if createdBy.Valid {
+			adj.CreatedBy = &createdBy.Int64
+		}
+		adjustments = append(adjustments, adj)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating adjustments: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+func (db *DB) GetAdjustmentTotal(userID int64, before time.Time) (float64, error) {
+	var total float64
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM balance_adjustments
+		WHERE user_id = ? AND created_at < ?
+	`, userID, before).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("error getting adjustment total: %w", err)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,346 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type AuditMeta struct {
+	ActorID   *int64
+	IPAddress string
+	RequestID string
+}
+
+type AuditFilter struct {
+	ActorID      int64
+	TargetUserID int64
+	Action       string
+	RequestID    string
+	Limit        int
+}
+
+type AuditChainError struct {
+	EventID int64
+	Reason  string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit event %d: %s", e.EventID, e.Reason)
+}
+
+func (db *DB) WithAudit(meta AuditMeta) *DB {
+	return &DB{DB: db.DB, audit: meta}
+}
+
+const auditColumns = `e.id, e.actor_id, e.action, e.method, e.path, e.status, e.target_user_id, e.reason,
+	e.details, e.before_state, e.after_state, e.ip_address, e.request_id, e.created_at, e.prev_hash, e.hash`
+
+func scanAuditEvent(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*AuditEvent, error) {
+	var e AuditEvent
+	var actorID, targetID sql.NullInt64
+	var details, before, after string
+	dest := append([]interface{}{&e.ID, &actorID, &e.Action, &e.Method, &e.Path, &e.Status, &targetID, &e.Reason,
+		&details, &before, &after, &e.IPAddress, &e.RequestID, &e.CreatedAt, &e.PrevHash, &e.Hash}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if actorID.Valid {
+		e.ActorID = &actorID.Int64
+	}
+	if targetID.Valid {
+		e.TargetUserID = &targetID.Int64
+	}
+	e.Details = json.RawMessage(details)
+	if before != "" {
+		e.Before = json.RawMessage(before)
+	}
+	if after != "" {
+		e.After = json.RawMessage(after)
+	}
+	return &e, nil
+}
+
+func (e *AuditEvent) computeHash() string {
+	payload, _ := json.Marshal(struct {
+		PrevHash     string `json:"prev_hash"`
+		CreatedAt    string `json:"created_at"`
+		ActorID      *int64 `json:"actor_id"`
+		Action       string `json:"action"`
+		Method       string `json:"method"`
+		Path         string `json:"path"`
+		Status       int    `json:"status"`
+		TargetUserID *int64 `json:"target_user_id"`
+		Reason       string `json:"reason"`
+		Details      string `json:"details"`
+		Before       string `json:"before"`
+		After        string `json:"after"`
+		IPAddress    string `json:"ip_address"`
+		RequestID    string `json:"request_id"`
+	}{
+		e.PrevHash, e.CreatedAt.UTC().Format(time.RFC3339Nano), e.ActorID, e.Action, e.Method, e.Path, e.Status,
+		e.TargetUserID, e.Reason, string(e.Details), string(e.Before), string(e.After), e.IPAddress, e.RequestID,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func appendAuditEvent(dbTx *sql.Tx, e *AuditEvent) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+	if len(e.Details) == 0 {
+		e.Details = json.RawMessage("{}")
+	}
+
+	err := dbTx.QueryRow(`SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&e.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error reading audit chain: %w", err)
+	}
+	e.Hash = e.computeHash()
+
+	err = dbTx.QueryRow(`
+		INSERT INTO audit_events (actor_id, action, method, path, status, target_user_id, reason,
+			details, before_state, after_state, ip_address, request_id, created_at, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, e.ActorID, e.Action, e.Method, e.Path, e.Status, e.TargetUserID, e.Reason,
+		string(e.Details), string(e.Before), string(e.After), e.IPAddress, e.RequestID, e.CreatedAt, e.PrevHash, e.Hash).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("error recording audit event: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) RecordAuditEvent(e *AuditEvent) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if err := appendAuditEvent(dbTx, e); err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) recordChange(dbTx *sql.Tx, action string, userID int64, before, after interface{}) error {
+	e := &AuditEvent{
+		ActorID:      db.audit.ActorID,
+		Action:       action,
+		TargetUserID: &userID,
+		IPAddress:    db.audit.IPAddress,
+		RequestID:    db.audit.RequestID,
+	}
+	for _, state := range []struct {
+		dest  *json.RawMessage
+		value interface{}
+	}{{&e.Before, before}, {&e.After, after}} {
+		if state.value == nil {
+			continue
+		}
+		b, err := json.Marshal(state.value)
+		if err != nil {
+			return fmt.Errorf("error encoding audit state: %w", err)
+		}
+		*state.dest = b
+	}
+	return appendAuditEvent(dbTx, e)
+}
+
+func balanceState(dbTx *sql.Tx, userID int64) (map[string]interface{}, error) {
+	var current float64
+	var swipes int
+	err := dbTx.QueryRow(`
+		SELECT current_balance, swipes_remaining FROM balances WHERE user_id = ?
+	`, userID).Scan(&current, &swipes)
+	if err != nil {
+		return nil, fmt.Errorf("error reading balance: %w", err)
+	}
+	return map[string]interface{}{"current_balance": current, "swipes_remaining": swipes}, nil
+}
+
+func (db *DB) ListAuditEvents(filter AuditFilter) ([]AuditEvent, error) {
+	where := "1 = 1"
+	var args []interface{}
+	if filter.ActorID != 0 {
+		where += " AND e.actor_id = ?"
+		args = append(args, filter.ActorID)
+	}
+	if filter.TargetUserID != 0 {
+		where += " AND e.target_user_id = ?"
+		args = append(args, filter.TargetUserID)
+	}
+	if filter.Action != "" {
+		where += " AND e.action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.RequestID != "" {
+		where += " AND e.request_id = ?"
+		args = append(args, filter.RequestID)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(`
+		SELECT `+auditColumns+`, COALESCE(a.student_id, ''), COALESCE(t.student_id, '')
+		FROM audit_events e
+		LEFT JOIN users a ON a.id = e.actor_id
+		LEFT JOIN users t ON t.id = e.target_user_id
+		WHERE `+where+`
+		ORDER BY e.id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []AuditEvent{}
+	for rows.Next() {
+		var actor, target string
+		e, err := scanAuditEvent(rows, &actor, &target)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning audit event: %w", err)
+		}
+		e.ActorStudentID, e.TargetStudentID = actor, target
+		events = append(events, *e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+func eachAuditEvent(q interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, fn func(*AuditEvent) error) error {
+	rows, err := q.Query(`SELECT ` + auditColumns + ` FROM audit_events e ORDER BY e.id`)
+	if err != nil {
+		return fmt.Errorf("error getting audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning audit event: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (db *DB) VerifyAuditChain() (int, string, error) {
+	count, prev := 0, ""
+	err := eachAuditEvent(db, func(e *AuditEvent) error {
+		if e.PrevHash != prev {
+			return &AuditChainError{EventID: e.ID, Reason: "previous hash does not match the preceding event, an event was removed or reordered"}
+		}
+		if e.Hash != e.computeHash() {
+			return &AuditChainError{EventID: e.ID, Reason: "hash does not match contents, the event was modified"}
+		}
+		prev = e.Hash
+		count++
+		return nil
+	})
+	return count, prev, err
+}
+
+func migrateAuditEvents(db *sql.DB) error {
+	sealed, err := hasColumn(db, "audit_events", "hash")
+	if err != nil {
+		return err
+	}
+
+	columns := []struct{ column, definition string }{
+		{"before_state", "TEXT NOT NULL DEFAULT ''"},
+		{"after_state", "TEXT NOT NULL DEFAULT ''"},
+		{"request_id", "TEXT NOT NULL DEFAULT ''"},
+		{"prev_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"hash", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, "audit_events", c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	if !sealed {
+		if err := sealAuditEvents(db); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.Exec(`
+		CREATE TRIGGER IF NOT EXISTS audit_events_no_update
+		BEFORE UPDATE ON audit_events
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_events is append-only');
+		END
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TRIGGER IF NOT EXISTS audit_events_no_delete
+		BEFORE DELETE ON audit_events
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_events is append-only');
+		END
+	`)
+	return err
+}
+
+func sealAuditEvents(db *sql.DB) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	var events []*AuditEvent
+	err = eachAuditEvent(dbTx, func(e *AuditEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	prev := ""
+	for _, e := range events {
+		e.CreatedAt = e.CreatedAt.UTC()
+		e.PrevHash = prev
+		e.Hash = e.computeHash()
+		_, err := dbTx.Exec(`
+			UPDATE audit_events SET created_at = ?, prev_hash = ?, hash = ? WHERE id = ?
+		`, e.CreatedAt, e.PrevHash, e.Hash, e.ID)
+		if err != nil {
+			return fmt.Errorf("error sealing audit event: %w", err)
+		}
+		prev = e.Hash
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
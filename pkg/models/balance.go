@@ -1,21 +1,28 @@
 package models
 
 import (
+	"database/sql"
 	"fmt"
+	"math"
 	"time"
 )
 
 func (db *DB) GetUserBalance(userID int64) (*Balance, error) {
 	var balance Balance
+	var swipesResetAt sql.NullTime
 	err := db.QueryRow(`
-		SELECT id, user_id, starting_balance, current_balance, updated_at
+		SELECT id, user_id, starting_balance, current_balance, swipes_remaining, swipe_allowance, swipe_reset, swipes_reset_at, updated_at
 		FROM balances
 		WHERE user_id = ?
-	`, userID).Scan(&balance.ID, &balance.UserID, &balance.StartingBalance, &balance.CurrentBalance, &balance.UpdatedAt)
+	`, userID).Scan(&balance.ID, &balance.UserID, &balance.StartingBalance, &balance.CurrentBalance,
+		&balance.SwipesRemaining, &balance.SwipeAllowance, &balance.SwipeReset, &swipesResetAt, &balance.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("error getting balance: %w", err)
 	}
+	if swipesResetAt.Valid {
+		balance.SwipesResetAt = &swipesResetAt.Time
+	}
 
 	return &balance, nil
 }
@@ -27,6 +34,11 @@ func (db *DB) UpdateBalance(userID int64, newAmount float64, tx *Transaction) er
 	}
 	defer dbTx.Rollback()
 
+	before, err := balanceState(dbTx, userID)
+	if err != nil {
+		return err
+	}
+
 	_, err = dbTx.Exec(`
 		UPDATE balances
 		SET current_balance = ?, updated_at = ?
@@ -37,15 +49,20 @@ func (db *DB) UpdateBalance(userID int64, newAmount float64, tx *Transaction) er
 		return fmt.Errorf("error updating balance: %w", err)
 	}
 
-	_, err = dbTx.Exec(`
-		INSERT INTO transactions (user_id, amount, location, description, transaction_date)
-		VALUES (?, ?, ?, ?, ?)
-	`, tx.UserID, tx.Amount, tx.Location, tx.Description, tx.TransactionDate)
+	err = dbTx.QueryRow(`
+		INSERT INTO transactions (user_id, amount, location, description, asset, transaction_date)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, tx.UserID, tx.Amount, tx.Location, tx.Description, AssetDollars, tx.TransactionDate).Scan(&tx.ID)
 
 	if err != nil {
 		return fmt.Errorf("error recording transaction: %w", err)
 	}
 
+	if err = db.recordTransaction(dbTx, tx, before); err != nil {
+		return err
+	}
+
 	if err = dbTx.Commit(); err != nil {
 		return fmt.Errorf("error committing transaction: %w", err)
 	}
@@ -56,12 +73,12 @@ func (db *DB) UpdateBalance(userID int64, newAmount float64, tx *Transaction) er
 func (db *DB) GetBudgetSettings(userID int64) (*BudgetSettings, error) {
 	var settings BudgetSettings
 	err := db.QueryRow(`
-		SELECT id, user_id, weekly_budget, budget_warnings, strict_budget, transaction_notifications, weekly_reports, updated_at
+		SELECT id, user_id, weekly_budget, budget_warnings, strict_budget, transaction_notifications, weekly_reports, weekly_swipe_limit, updated_at
 		FROM budget_settings
 		WHERE user_id = ?
 	`, userID).Scan(
 		&settings.ID, &settings.UserID, &settings.WeeklyBudget, &settings.BudgetWarnings,
-		&settings.StrictBudget, &settings.TransactionNotifications, &settings.WeeklyReports, &settings.UpdatedAt,
+		&settings.StrictBudget, &settings.TransactionNotifications, &settings.WeeklyReports, &settings.WeeklySwipeLimit, &settings.UpdatedAt,
 	)
 
 	if err != nil {
@@ -72,14 +89,27 @@ func (db *DB) GetBudgetSettings(userID int64) (*BudgetSettings, error) {
 }
 
 func (db *DB) UpdateBudgetSettings(settings *BudgetSettings) error {
-	_, err := db.Exec(`
+	before, err := db.GetBudgetSettings(settings.UserID)
+	if err != nil {
+		return err
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	settings.ID = before.ID
+	settings.UpdatedAt = time.Now()
+	_, err = dbTx.Exec(`
 		UPDATE budget_settings
 		SET weekly_budget = ?, budget_warnings = ?, strict_budget = ?, 
-		    transaction_notifications = ?, weekly_reports = ?, updated_at = ?
+		    transaction_notifications = ?, weekly_reports = ?, weekly_swipe_limit = ?, updated_at = ?
 		WHERE user_id = ?
 	`,
 		settings.WeeklyBudget, settings.BudgetWarnings, settings.StrictBudget,
-		settings.TransactionNotifications, settings.WeeklyReports, time.Now(),
+		settings.TransactionNotifications, settings.WeeklyReports, settings.WeeklySwipeLimit, settings.UpdatedAt,
 		settings.UserID,
 	)
 
@@ -87,5 +117,95 @@ func (db *DB) UpdateBudgetSettings(settings *BudgetSettings) error {
 		return fmt.Errorf("error updating budget settings: %w", err)
 	}
 
+	if err = db.recordChange(dbTx, "budget.update", settings.UserID, before, settings); err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
 	return nil
-} 
\ No newline at end of file
+} 
+func recalculateBalance(dbTx *sql.Tx, userID int64) (float64, error) {
+	var balance float64
+	err := dbTx.QueryRow(`
+		SELECT b.starting_balance
+			+ COALESCE((SELECT SUM(amount) FROM deposits WHERE user_id = b.user_id AND status = ?), 0)
+			+ COALESCE((SELECT SUM(amount) FROM balance_adjustments WHERE user_id = b.user_id), 0)
+			- COALESCE((SELECT SUM(amount) FROM transactions WHERE user_id = b.user_id AND asset = ?), 0)
+		FROM balances b
+		WHERE b.user_id = ?
+	`, DepositSettled, AssetDollars, userID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("error calculating balance: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		UPDATE balances
+		SET current_balance = ?, updated_at = ?
+		WHERE user_id = ?
+	`, balance, time.Now(), userID)
+	if err != nil {
+		return 0, fmt.Errorf("error updating balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+func (db *DB) GetBalanceAt(userID int64, at time.Time) (float64, error) {
+	return balanceAt(db, userID, at)
+}
+
+func balanceAt(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, userID int64, at time.Time) (float64, error) {
+	var balance float64
+	err := q.QueryRow(`
+		SELECT b.starting_balance
+			+ COALESCE((SELECT SUM(amount) FROM deposits WHERE user_id = b.user_id AND status = ? AND settled_at < ?), 0)
+			+ COALESCE((SELECT SUM(amount) FROM balance_adjustments WHERE user_id = b.user_id AND created_at < ?), 0)
+			- COALESCE((SELECT SUM(amount) FROM transactions WHERE user_id = b.user_id AND asset = ? AND transaction_date < ?), 0)
+		FROM balances b
+		WHERE b.user_id = ?
+	`, DepositSettled, at, at, AssetDollars, at, userID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("error calculating balance: %w", err)
+	}
+	return roundCents(balance), nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func (db *DB) ResetBudgetSettings(userID int64, at time.Time) (*BudgetSettings, error) {
+	term, err := db.EnsureTerm(at)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *MealPlan
+	current, err := db.GetUserMealPlan(userID, term.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		plan = &current.MealPlan
+	} else if plan, err = db.GetDefaultMealPlan(term.Kind); err != nil {
+		return nil, fmt.Errorf("error getting default meal plan: %w", err)
+	}
+
+	settings := &BudgetSettings{
+		UserID:                   userID,
+		WeeklyBudget:             DefaultWeeklyBudget(plan, term),
+		BudgetWarnings:           true,
+		TransactionNotifications: true,
+		WeeklyReports:            true,
+	}
+	if err := db.UpdateBudgetSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return db.GetBudgetSettings(userID)
+}
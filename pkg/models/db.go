@@ -3,8 +3,10 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -12,6 +14,7 @@ import (
 
 type DB struct {
 	*sql.DB
+	audit AuditMeta
 }
 
 func InitDB() (*DB, error) {
@@ -20,7 +23,12 @@ func InitDB() (*DB, error) {
 		dbPath = "flexibudget.db"
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+
+	db, err := sql.Open("sqlite3", dbPath+sep+"_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %w", err)
 	}
@@ -33,7 +41,11 @@ func InitDB() (*DB, error) {
 		return nil, fmt.Errorf("error creating tables: %w", err)
 	}
 
-	return &DB{db}, nil
+	if err = seedMealPlans(db); err != nil {
+		return nil, fmt.Errorf("error seeding meal plans: %w", err)
+	}
+
+	return &DB{DB: db}, nil
 }
 
 func createTables(db *sql.DB) error {
@@ -98,9 +110,403 @@ func createTables(db *sql.DB) error {
 		return err
 	}
 
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS deposits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reference TEXT UNIQUE NOT NULL,
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			amount REAL NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			provider TEXT NOT NULL DEFAULT '',
+			provider_reference TEXT NOT NULL DEFAULT '',
+			note TEXT NOT NULL DEFAULT '',
+			failure_reason TEXT NOT NULL DEFAULT '',
+			created_by INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			settled_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id),
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS terms (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			kind TEXT NOT NULL,
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS meal_plans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			term TEXT NOT NULL,
+			price REAL NOT NULL,
+			dining_dollars REAL NOT NULL,
+			meal_swipes INTEGER NOT NULL DEFAULT 0,
+			is_default BOOLEAN NOT NULL DEFAULT 0,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_meal_plans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			term_id INTEGER NOT NULL,
+			meal_plan_id INTEGER NOT NULL,
+			effective_from TIMESTAMP NOT NULL,
+			effective_to TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id),
+			FOREIGN KEY (term_id) REFERENCES terms (id),
+			FOREIGN KEY (meal_plan_id) REFERENCES meal_plans (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS balance_adjustments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			amount REAL NOT NULL,
+			reason TEXT NOT NULL,
+			term_id INTEGER,
+			created_by INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id),
+			FOREIGN KEY (term_id) REFERENCES terms (id),
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS transaction_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id INTEGER NOT NULL,
+			menu_item_id INTEGER,
+			name TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			unit_price REAL NOT NULL,
+			discount REAL NOT NULL DEFAULT 0,
+			tax REAL NOT NULL DEFAULT 0,
+			total REAL NOT NULL,
+			FOREIGN KEY (transaction_id) REFERENCES transactions (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			public_id TEXT UNIQUE NOT NULL,
+			user_id INTEGER NOT NULL,
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP,
+			revoked_reason TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			FOREIGN KEY (session_id) REFERENCES sessions (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_roles (
+			user_id INTEGER NOT NULL,
+			role TEXT NOT NULL,
+			granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, role),
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO user_roles (user_id, role)
+		SELECT id, ? FROM users
+		WHERE id NOT IN (SELECT user_id FROM user_roles)
+	`, DefaultRole)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			actor_id INTEGER,
+			action TEXT NOT NULL,
+			method TEXT NOT NULL DEFAULT '',
+			path TEXT NOT NULL DEFAULT '',
+			status INTEGER NOT NULL DEFAULT 0,
+			target_user_id INTEGER,
+			reason TEXT NOT NULL DEFAULT '',
+			details TEXT NOT NULL DEFAULT '{}',
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (actor_id) REFERENCES users (id),
+			FOREIGN KEY (target_user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS password_reset_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS email_verification_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			email TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS login_throttles (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP NOT NULL,
+			locked_until TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_totp (
+			user_id INTEGER PRIMARY KEY,
+			secret TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			enabled_at TIMESTAMP,
+			last_used_step INTEGER NOT NULL DEFAULT 0,
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			code_hash TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			used_at TIMESTAMP,
+			UNIQUE (user_id, code_hash),
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS login_challenges (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			issuer TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (issuer, subject),
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS oidc_logins (
+			state_hash TEXT PRIMARY KEY,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS pending_identity_links (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			issuer TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if err = migrateAuditEvents(db); err != nil {
+		return err
+	}
+
+	verified, err := hasColumn(db, "users", "email_verified_at")
+	if err != nil {
+		return err
+	}
+
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"transactions", "asset", "TEXT NOT NULL DEFAULT 'dollars'"},
+		{"balances", "swipes_remaining", "INTEGER NOT NULL DEFAULT 0"},
+		{"balances", "swipe_allowance", "INTEGER NOT NULL DEFAULT 0"},
+		{"balances", "swipe_reset", "TEXT NOT NULL DEFAULT 'term'"},
+		{"balances", "swipes_reset_at", "TIMESTAMP"},
+		{"meal_plans", "swipe_reset", "TEXT NOT NULL DEFAULT 'term'"},
+		{"budget_settings", "weekly_swipe_limit", "INTEGER NOT NULL DEFAULT 0"},
+		{"meal_plans", "closeout_rule", "TEXT NOT NULL DEFAULT 'forfeit'"},
+		{"meal_plans", "rollover_cap", "REAL NOT NULL DEFAULT 0"},
+		{"meal_plans", "conversion_rate", "REAL NOT NULL DEFAULT 0"},
+		{"transaction_items", "calories", "REAL NOT NULL DEFAULT 0"},
+		{"transaction_items", "protein_g", "REAL NOT NULL DEFAULT 0"},
+		{"transaction_items", "carbs_g", "REAL NOT NULL DEFAULT 0"},
+		{"transaction_items", "fat_g", "REAL NOT NULL DEFAULT 0"},
+		{"terms", "closed_at", "TIMESTAMP"},
+		{"users", "locked_at", "TIMESTAMP"},
+		{"users", "locked_reason", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "email_verified_at", "TIMESTAMP"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	if !verified {
+		if _, err = db.Exec(`UPDATE users SET email_verified_at = created_at`); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (db *DB) AddColumnIfMissing(table, column, definition string) error {
+	return addColumnIfMissing(db.DB, table, column, definition)
+}
+
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	exists, err := hasColumn(db, table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
@@ -113,6 +519,9 @@ type User struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	PasswordHash string    `json:"-"`
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	LockedReason string    `json:"locked_reason,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 }
 
 type Balance struct {
@@ -120,6 +529,10 @@ type Balance struct {
 	UserID         int64     `json:"user_id"`
 	StartingBalance float64   `json:"starting_balance"`
 	CurrentBalance float64   `json:"current_balance"`
+	SwipesRemaining int        `json:"swipes_remaining"`
+	SwipeAllowance  int        `json:"swipe_allowance"`
+	SwipeReset      string     `json:"swipe_reset"`
+	SwipesResetAt   *time.Time `json:"swipes_reset_at,omitempty"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
@@ -131,6 +544,7 @@ type BudgetSettings struct {
 	StrictBudget           bool      `json:"strict_budget"`
 	TransactionNotifications bool     `json:"transaction_notifications"`
 	WeeklyReports          bool      `json:"weekly_reports"`
+	WeeklySwipeLimit       int       `json:"weekly_swipe_limit"`
 	UpdatedAt              time.Time `json:"updated_at"`
 }
 
@@ -140,5 +554,122 @@ type Transaction struct {
 	Amount          float64   `json:"amount"`
 	Location        string    `json:"location"`
 	Description     string    `json:"description"`
+	Asset           string    `json:"asset"`
 	TransactionDate time.Time `json:"transaction_date"`
-} 
\ No newline at end of file
+	Items           []TransactionItem `json:"items,omitempty"`
+}
+
+type TransactionItem struct {
+	ID            int64   `json:"id"`
+	TransactionID int64   `json:"transaction_id"`
+	MenuItemID    *int64  `json:"menu_item_id,omitempty"`
+	Name          string  `json:"name"`
+	Quantity      int     `json:"quantity"`
+	UnitPrice     float64 `json:"unit_price"`
+	Discount      float64 `json:"discount"`
+	Tax           float64 `json:"tax"`
+	Total         float64 `json:"total"`
+	Nutrition     Nutrition `json:"nutrition"`
+}
+
+type Nutrition struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein_g"`
+	Carbs    float64 `json:"carbs_g"`
+	Fat      float64 `json:"fat_g"`
+}
+
+type Deposit struct {
+	ID                int64      `json:"id"`
+	Reference         string     `json:"reference"`
+	UserID            int64      `json:"user_id"`
+	Source            string     `json:"source"`
+	Amount            float64    `json:"amount"`
+	Status            string     `json:"status"`
+	Provider          string     `json:"provider,omitempty"`
+	ProviderReference string     `json:"provider_reference,omitempty"`
+	Note              string     `json:"note,omitempty"`
+	FailureReason     string     `json:"failure_reason,omitempty"`
+	CreatedBy         *int64     `json:"created_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	SettledAt         *time.Time `json:"settled_at,omitempty"`
+}
+
+type Term struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   time.Time  `json:"ends_at"`
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+}
+
+type MealPlan struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Term          string    `json:"term"`
+	Price         float64   `json:"price"`
+	DiningDollars float64   `json:"dining_dollars"`
+	MealSwipes    int       `json:"meal_swipes"`
+	SwipeReset    string    `json:"swipe_reset"`
+	CloseoutRule  string    `json:"closeout_rule"`
+	RolloverCap   float64   `json:"rollover_cap"`
+	ConversionRate float64  `json:"conversion_rate"`
+	IsDefault     bool      `json:"is_default"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type UserMealPlan struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Term          Term       `json:"term"`
+	MealPlan      MealPlan   `json:"meal_plan"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+}
+
+type Session struct {
+	ID            int64      `json:"-"`
+	PublicID      string     `json:"id"`
+	UserID        int64      `json:"user_id"`
+	UserAgent     string     `json:"user_agent"`
+	IPAddress     string     `json:"ip_address"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+}
+
+type BalanceAdjustment struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Kind      string    `json:"kind"`
+	Amount    float64   `json:"amount"`
+	Reason    string    `json:"reason"`
+	TermID    *int64    `json:"term_id,omitempty"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditEvent struct {
+	ID              int64           `json:"id"`
+	ActorID         *int64          `json:"actor_id,omitempty"`
+	ActorStudentID  string          `json:"actor_student_id,omitempty"`
+	Action          string          `json:"action"`
+	Method          string          `json:"method,omitempty"`
+	Path            string          `json:"path,omitempty"`
+	Status          int             `json:"status,omitempty"`
+	TargetUserID    *int64          `json:"target_user_id,omitempty"`
+	TargetStudentID string          `json:"target_student_id,omitempty"`
+	Reason          string          `json:"reason,omitempty"`
+	Details         json.RawMessage `json:"details,omitempty"`
+	Before          json.RawMessage `json:"before,omitempty"`
+	After           json.RawMessage `json:"after,omitempty"`
+	IPAddress       string          `json:"ip_address,omitempty"`
+	RequestID       string          `json:"request_id,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	PrevHash        string          `json:"prev_hash"`
+	Hash            string          `json:"hash"`
+}
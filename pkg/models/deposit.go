@@ -0,0 +1,259 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DepositSourceTopUp       = "topup"
+	DepositSourceAdminCredit = "admin_credit"
+	DepositSourcePlanLoad    = "plan_load"
+
+	DepositPending = "pending"
+	DepositSettled = "settled"
+	DepositFailed  = "failed"
+)
+
+var ErrDepositNotPending = errors.New("deposit is not pending")
+
+const depositColumns = `id, reference, user_id, source, amount, status, provider, provider_reference,
+	note, failure_reason, created_by, created_at, settled_at`
+
+func scanDeposit(row interface{ Scan(...interface{}) error }) (*Deposit, error) {
+	var d Deposit
+	var createdBy sql.NullInt64
+	var settledAt sql.NullTime
+	err := row.Scan(
+		&d.ID, &d.Reference, &d.UserID, &d.Source, &d.Amount, &d.Status, &d.Provider, &d.ProviderReference,
+		&d.Note, &d.FailureReason, &createdBy, &d.CreatedAt, &settledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		d.CreatedBy = &createdBy.Int64
+	}
+	if settledAt.Valid {
+		d.SettledAt = &settledAt.Time
+	}
+	return &d, nil
+}
+
+func newDepositReference() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "dep_" + hex.EncodeToString(b), nil
+}
+
+func (db *DB) CreateDeposit(userID int64, source string, amount float64, provider, note string, createdBy *int64) (*Deposit, error) {
+	reference, err := newDepositReference()
+	if err != nil {
+		return nil, fmt.Errorf("error generating deposit reference: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO deposits (reference, user_id, source, amount, status, provider, note, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, reference, userID, source, amount, DepositPending, provider, note, createdBy, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating deposit: %w", err)
+	}
+
+	return db.GetDepositByReference(reference)
+}
+
+func (db *DB) GetDepositByReference(reference string) (*Deposit, error) {
+	d, err := scanDeposit(db.QueryRow(`SELECT `+depositColumns+` FROM deposits WHERE reference = ?`, reference))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting deposit: %w", err)
+	}
+	return d, nil
+}
+
+func (db *DB) GetUserDeposits(userID int64) ([]Deposit, error) {
+	rows, err := db.Query(`
+		SELECT `+depositColumns+`
+		FROM deposits
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting deposits: %w", err)
+	}
+	defer rows.Close()
+
+	var deposits []Deposit
+	for rows.Next() {
+		d, err := scanDeposit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning deposit: %w", err)
+		}
+		deposits = append(deposits, *d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deposits: %w", err)
+	}
+
+	return deposits, nil
+}
+
+func (db *DB) GetPendingDeposits(provider string) ([]Deposit, error) {
+	rows, err := db.Query(`
+		SELECT `+depositColumns+`
+		FROM deposits
+		WHERE status = ? AND provider = ?
+		ORDER BY created_at ASC
+	`, DepositPending, provider)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pending deposits: %w", err)
+	}
+	defer rows.Close()
+
+	var deposits []Deposit
+	for rows.Next() {
+		d, err := scanDeposit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning deposit: %w", err)
+		}
+		deposits = append(deposits, *d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deposits: %w", err)
+	}
+
+	return deposits, nil
+}
+
+func (db *DB) GetSettledDepositsBetween(userID int64, start, end time.Time) ([]Deposit, error) {
+	rows, err := db.Query(`
+		SELECT `+depositColumns+`
+		FROM deposits
+		WHERE user_id = ? AND status = ? AND settled_at >= ? AND settled_at < ?
+		ORDER BY settled_at ASC, id ASC
+	`, userID, DepositSettled, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting deposits: %w", err)
+	}
+	defer rows.Close()
+
+	var deposits []Deposit
+	for rows.Next() {
+		d, err := scanDeposit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning deposit: %w", err)
+		}
+		deposits = append(deposits, *d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deposits: %w", err)
+	}
+
+	return deposits, nil
+}
+
+func (db *DB) GetSettledDepositTotal(userID int64, before time.Time) (float64, error) {
+	var total float64
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM deposits
+		WHERE user_id = ? AND status = ? AND settled_at < ?
+	`, userID, DepositSettled, before).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("error getting deposit total: %w", err)
+	}
+
+	return total, nil
+}
+
+func (db *DB) SettleDeposit(reference, providerReference string) (*Deposit, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	var userID int64
+	var amount float64
+	err = dbTx.QueryRow(`
+		UPDATE deposits
+		SET status = ?, provider_reference = ?, settled_at = ?
+		WHERE reference = ? AND status = ?
+		RETURNING user_id, amount
+	`, DepositSettled, providerReference, time.Now(), reference, DepositPending).Scan(&userID, &amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDepositNotPending
+		}
+		return nil, fmt.Errorf("error settling deposit: %w", err)
+	}
+
+	before, err := balanceState(dbTx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dbTx.Exec(`
+		UPDATE balances
+		SET current_balance = current_balance + ?, updated_at = ?
+		WHERE user_id = ?
+	`, amount, time.Now(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating balance: %w", err)
+	}
+
+	deposit, err := scanDeposit(dbTx.QueryRow(`SELECT `+depositColumns+` FROM deposits WHERE reference = ?`, reference))
+	if err != nil {
+		return nil, fmt.Errorf("error getting deposit: %w", err)
+	}
+	after, err := balanceState(dbTx, userID)
+	if err != nil {
+		return nil, err
+	}
+	after["deposit"] = deposit
+	if err = db.recordChange(dbTx, "deposit.settled", userID, before, after); err != nil {
+		return nil, err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return deposit, nil
+}
+
+func (db *DB) FailDeposit(reference, providerReference, reason string) (*Deposit, error) {
+	result, err := db.Exec(`
+		UPDATE deposits
+		SET status = ?, provider_reference = ?, failure_reason = ?
+		WHERE reference = ? AND status = ?
+	`, DepositFailed, providerReference, reason, reference, DepositPending)
+	if err != nil {
+		return nil, fmt.Errorf("error failing deposit: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrDepositNotPending
+	}
+
+	return db.GetDepositByReference(reference)
+}
+
+func (db *DB) SetDepositProviderReference(reference, providerReference string) error {
+	_, err := db.Exec(`UPDATE deposits SET provider_reference = ? WHERE reference = ?`, providerReference, reference)
+	if err != nil {
+		return fmt.Errorf("error updating deposit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "models.db"))
+	db, err := InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestUser(t *testing.T, db *DB, studentID string) *User {
+	t.Helper()
+
+	user, err := db.CreateUser(studentID, "Test "+studentID, studentID+"@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func currentBalance(t *testing.T, db *DB, userID int64) float64 {
+	t.Helper()
+
+	balance, err := db.GetUserBalance(userID)
+	if err != nil {
+		t.Fatalf("GetUserBalance: %v", err)
+	}
+	return balance.CurrentBalance
+}
+
+func TestSettleDeposit(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "60000001")
+	admin := newTestUser(t, db, "60000002")
+	start := currentBalance(t, db, user.ID)
+
+	tests := []struct {
+		source string
+		amount float64
+		total  float64
+	}{
+		{DepositSourceTopUp, 25.10, 25.10},
+		{DepositSourceAdminCredit, 0.20, 25.30},
+		{DepositSourcePlanLoad, 149.99, 175.29},
+	}
+	for _, tt := range tests {
+		deposit, err := db.CreateDeposit(user.ID, tt.source, tt.amount, "", "Test", &admin.ID)
+		if err != nil {
+			t.Fatalf("CreateDeposit: %v", err)
+		}
+		settled, err := db.WithAudit(AuditMeta{ActorID: &admin.ID}).SettleDeposit(deposit.Reference, "ref_"+deposit.Reference)
+		if err != nil {
+			t.Fatalf("SettleDeposit: %v", err)
+		}
+		if settled.Status != DepositSettled || settled.SettledAt == nil || settled.Amount != tt.amount {
+			t.Fatalf("settled deposit = %+v", settled)
+		}
+		if got := roundCents(currentBalance(t, db, user.ID) - start); got != tt.total {
+			t.Errorf("after %s of %.2f: balance change %.2f, want %.2f", tt.source, tt.amount, got, tt.total)
+		}
+
+		if _, err := db.SettleDeposit(deposit.Reference, ""); err != ErrDepositNotPending {
+			t.Errorf("settling twice: err = %v, want ErrDepositNotPending", err)
+		}
+	}
+
+	events, err := db.ListAuditEvents(AuditFilter{TargetUserID: user.ID, Action: "deposit.settled"})
+	if err != nil {
+		t.Fatalf("ListAuditEvents: %v", err)
+	}
+	if len(events) != len(tests) {
+		t.Fatalf("deposit.settled events = %d, want %d", len(events), len(tests))
+	}
+	for _, e := range events {
+		if e.ActorID == nil || *e.ActorID != admin.ID {
+			t.Errorf("event %d actor = %v, want %d", e.ID, e.ActorID, admin.ID)
+		}
+		var before, after map[string]interface{}
+		if err := json.Unmarshal(e.Before, &before); err != nil {
+			t.Fatalf("before state: %v", err)
+		}
+		if err := json.Unmarshal(e.After, &after); err != nil {
+			t.Fatalf("after state: %v", err)
+		}
+		deposit, _ := after["deposit"].(map[string]interface{})
+		change := roundCents(after["current_balance"].(float64) - before["current_balance"].(float64))
+		if deposit == nil || change != deposit["amount"] {
+			t.Errorf("event %d: balance change %.2f does not match deposit %v", e.ID, change, deposit)
+		}
+	}
+
+	if _, _, err := db.VerifyAuditChain(); err != nil {
+		t.Fatalf("VerifyAuditChain: %v", err)
+	}
+}
+
+func TestFailedDepositDoesNotCredit(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "60000003")
+	start := currentBalance(t, db, user.ID)
+
+	deposit, err := db.CreateDeposit(user.ID, DepositSourceTopUp, 40, "fake", "", nil)
+	if err != nil {
+		t.Fatalf("CreateDeposit: %v", err)
+	}
+	if _, err := db.FailDeposit(deposit.Reference, "", "card declined"); err != nil {
+		t.Fatalf("FailDeposit: %v", err)
+	}
+	if _, err := db.SettleDeposit(deposit.Reference, ""); err != ErrDepositNotPending {
+		t.Fatalf("settling a failed deposit: err = %v, want ErrDepositNotPending", err)
+	}
+	if got := currentBalance(t, db, user.ID); got != start {
+		t.Fatalf("balance = %.2f, want %.2f", got, start)
+	}
+}
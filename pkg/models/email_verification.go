@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrVerificationTokenInvalid = errors.New("email verification token is invalid or expired")
+
+func (db *DB) CreateEmailVerificationToken(userID int64, email, tokenHash string, expiresAt time.Time) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	now := time.Now()
+	_, err = dbTx.Exec(`
+		UPDATE email_verification_tokens SET used_at = ?
+		WHERE user_id = ? AND used_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return fmt.Errorf("error expiring verification tokens: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		INSERT INTO email_verification_tokens (user_id, email, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, email, tokenHash, now, expiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating verification token: %w", err)
+	}
+
+	err = db.recordChange(dbTx, "email.verification_sent", userID, nil, map[string]interface{}{"email": email, "expires_at": expiresAt})
+	if err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) EmailVerificationRequests(userID int64, since time.Time) (int, time.Time, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM email_verification_tokens
+		WHERE user_id = ? AND created_at > ?
+	`, userID, since).Scan(&count)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("error getting verification tokens: %w", err)
+	}
+
+	var last time.Time
+	err = db.QueryRow(`
+		SELECT created_at FROM email_verification_tokens
+		WHERE user_id = ?
+		ORDER BY created_at DESC LIMIT 1
+	`, userID).Scan(&last)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, time.Time{}, fmt.Errorf("error getting verification tokens: %w", err)
+	}
+
+	return count, last, nil
+}
+
+func (db *DB) VerifyEmail(tokenHash string, at time.Time) (*User, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	var tokenID, userID int64
+	var email string
+	err = dbTx.QueryRow(`
+		SELECT t.id, t.user_id, t.email FROM email_verification_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = ? AND t.used_at IS NULL AND t.expires_at > ? AND t.email = u.email
+	`, tokenHash, at).Scan(&tokenID, &userID, &email)
+	if err == sql.ErrNoRows {
+		return nil, ErrVerificationTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting verification token: %w", err)
+	}
+
+	if _, err = dbTx.Exec(`UPDATE email_verification_tokens SET used_at = ? WHERE id = ?`, at, tokenID); err != nil {
+		return nil, fmt.Errorf("error using verification token: %w", err)
+	}
+
+	if err = markEmailVerified(dbTx, userID, at); err != nil {
+		return nil, err
+	}
+
+	err = db.recordChange(dbTx, "email.verified", userID, nil, map[string]interface{}{"email": email})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return db.GetUserByID(userID)
+}
+
+func (db *DB) MarkEmailVerified(userID int64, at time.Time) error {
+	return markEmailVerified(db, userID, at)
+}
+
+func markEmailVerified(q interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}, userID int64, at time.Time) error {
+	_, err := q.Exec(`
+		UPDATE users SET email_verified_at = ?, updated_at = ?
+		WHERE id = ? AND email_verified_at IS NULL
+	`, at, at, userID)
+	if err != nil {
+		return fmt.Errorf("error verifying email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrOIDCStateInvalid    = errors.New("single sign-on state is invalid or expired")
+	ErrIdentityLinkInvalid = errors.New("identity link is invalid or expired")
+)
+
+type OIDCLogin struct {
+	Nonce        string
+	CodeVerifier string
+}
+
+func (db *DB) GetUserByIdentity(issuer, subject string) (*User, error) {
+	var userID int64
+	err := db.QueryRow(`
+		SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?
+	`, issuer, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting identity: %w", err)
+	}
+	return db.GetUserByID(userID)
+}
+
+func (db *DB) LinkIdentity(userID int64, issuer, subject string) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if err = db.linkIdentity(dbTx, userID, issuer, subject); err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) linkIdentity(dbTx *sql.Tx, userID int64, issuer, subject string) error {
+	_, err := dbTx.Exec(`
+		INSERT INTO user_identities (user_id, issuer, subject, created_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, issuer, subject, time.Now())
+	if err != nil {
+		return fmt.Errorf("error linking identity: %w", err)
+	}
+
+	return db.recordChange(dbTx, "identity.link", userID, nil, map[string]interface{}{"issuer": issuer, "subject": subject})
+}
+
+func (db *DB) CreatePendingIdentityLink(tokenHash string, userID int64, issuer, subject string, expiresAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO pending_identity_links (token_hash, user_id, issuer, subject, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, tokenHash, userID, issuer, subject, time.Now(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating pending identity link: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) ConfirmIdentityLink(tokenHash string, userID int64, at time.Time) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	var issuer, subject string
+	err = dbTx.QueryRow(`
+		SELECT issuer, subject FROM pending_identity_links
+		WHERE token_hash = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?
+	`, tokenHash, userID, at).Scan(&issuer, &subject)
+	if err == sql.ErrNoRows {
+		return ErrIdentityLinkInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("error getting pending identity link: %w", err)
+	}
+
+	if _, err = dbTx.Exec(`UPDATE pending_identity_links SET used_at = ? WHERE token_hash = ?`, at, tokenHash); err != nil {
+		return fmt.Errorf("error using pending identity link: %w", err)
+	}
+
+	if err = db.linkIdentity(dbTx, userID, issuer, subject); err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) CreateOIDCLogin(stateHash string, login OIDCLogin, expiresAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO oidc_logins (state_hash, nonce, code_verifier, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, stateHash, login.Nonce, login.CodeVerifier, time.Now(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating single sign-on state: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) ConsumeOIDCLogin(stateHash string, at time.Time) (*OIDCLogin, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	var login OIDCLogin
+	err = dbTx.QueryRow(`
+		SELECT nonce, code_verifier FROM oidc_logins
+		WHERE state_hash = ? AND used_at IS NULL AND expires_at > ?
+	`, stateHash, at).Scan(&login.Nonce, &login.CodeVerifier)
+	if err == sql.ErrNoRows {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting single sign-on state: %w", err)
+	}
+
+	if _, err = dbTx.Exec(`UPDATE oidc_logins SET used_at = ? WHERE state_hash = ?`, at, stateHash); err != nil {
+		return nil, fmt.Errorf("error using single sign-on state: %w", err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return &login, nil
+}
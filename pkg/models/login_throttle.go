@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type LoginThrottle struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+func (t *LoginThrottle) Locked(at time.Time) bool {
+	return t != nil && t.LockedUntil != nil && t.LockedUntil.After(at)
+}
+
+const loginThrottleColumns = `key, failures, last_failure_at, locked_until`
+
+func scanLoginThrottle(row interface{ Scan(...interface{}) error }) (*LoginThrottle, error) {
+	var t LoginThrottle
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&t.Key, &t.Failures, &t.LastFailureAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		t.LockedUntil = &lockedUntil.Time
+	}
+	return &t, nil
+}
+
+func (db *DB) GetLoginThrottle(key string) (*LoginThrottle, error) {
+	t, err := scanLoginThrottle(db.QueryRow(`
+		SELECT `+loginThrottleColumns+` FROM login_throttles WHERE key = ?
+	`, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting login throttle: %w", err)
+	}
+	return t, nil
+}
+
+func (db *DB) RecordLoginFailure(key string, at time.Time, window time.Duration, lockFor func(failures int) time.Duration) (*LoginThrottle, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	t, err := scanLoginThrottle(dbTx.QueryRow(`
+		SELECT `+loginThrottleColumns+` FROM login_throttles WHERE key = ?
+	`, key))
+	if err == sql.ErrNoRows || (err == nil && at.Sub(t.LastFailureAt) > window) {
+		t, err = &LoginThrottle{Key: key}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting login throttle: %w", err)
+	}
+
+	t.Failures++
+	t.LastFailureAt = at
+	t.LockedUntil = nil
+	if d := lockFor(t.Failures); d > 0 {
+		until := at.Add(d)
+		t.LockedUntil = &until
+	}
+
+	_, err = dbTx.Exec(`
+		INSERT INTO login_throttles (key, failures, last_failure_at, locked_until)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = excluded.failures,
+			last_failure_at = excluded.last_failure_at,
+			locked_until = excluded.locked_until
+	`, t.Key, t.Failures, t.LastFailureAt, t.LockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("error recording login failure: %w", err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return t, nil
+}
+
+func (db *DB) ClearLoginThrottle(key string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM login_throttles WHERE key = ?`, key)
+	if err != nil {
+		return false, fmt.Errorf("error clearing login throttle: %w", err)
+	}
+	cleared, _ := result.RowsAffected()
+	return cleared > 0, nil
+}
+
+func (db *DB) ListLoginLockouts(at time.Time) ([]LoginThrottle, error) {
+	rows, err := db.Query(`
+		SELECT `+loginThrottleColumns+` FROM login_throttles
+		WHERE locked_until > ?
+		ORDER BY locked_until DESC
+	`, at)
+	if err != nil {
+		return nil, fmt.Errorf("error getting login lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	lockouts := []LoginThrottle{}
+	for rows.Next() {
+		t, err := scanLoginThrottle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning login lockout: %w", err)
+		}
+		lockouts = append(lockouts, *t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating login lockouts: %w", err)
+	}
+
+	return lockouts, nil
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	CloseoutForfeit  = "forfeit"
+	CloseoutRollover = "rollover"
+	CloseoutConvert  = "convert"
+)
+
+var (
+	ErrNoDefaultMealPlan   = errors.New("no default meal plan configured")
+	ErrMealPlanUnavailable = errors.New("meal plan is not available for this term")
+	ErrSameMealPlan        = errors.New("user is already on this meal plan")
+)
+
+func seedMealPlans(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM meal_plans`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	plans := []MealPlan{
+		{Name: "Standard", Term: TermKindSemester, Price: 1500.00, DiningDollars: 1500.00, IsDefault: true,
+			CloseoutRule: CloseoutRollover, RolloverCap: 300.00},
+		{Name: "Light", Term: TermKindSemester, Price: 900.00, DiningDollars: 900.00,
+			CloseoutRule: CloseoutRollover, RolloverCap: 150.00},
+		{Name: "Premium", Term: TermKindSemester, Price: 2200.00, DiningDollars: 2200.00,
+			CloseoutRule: CloseoutRollover},
+		{Name: "Block 14", Term: TermKindSemester, Price: 2600.00, DiningDollars: 400.00, MealSwipes: 14, SwipeReset: SwipeResetWeekly,
+			CloseoutRule: CloseoutForfeit},
+		{Name: "Block 150", Term: TermKindSemester, Price: 2100.00, DiningDollars: 500.00, MealSwipes: 150, SwipeReset: SwipeResetTerm,
+			CloseoutRule: CloseoutConvert, RolloverCap: 200.00, ConversionRate: 4.00},
+		{Name: "Summer", Term: TermKindSummer, Price: 600.00, DiningDollars: 600.00, IsDefault: true,
+			CloseoutRule: CloseoutForfeit},
+	}
+
+	for _, p := range plans {
+		if p.SwipeReset == "" {
+			p.SwipeReset = SwipeResetTerm
+		}
+		_, err := db.Exec(`
+			INSERT INTO meal_plans (name, term, price, dining_dollars, meal_swipes, swipe_reset,
+				closeout_rule, rollover_cap, conversion_rate, is_default, active, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)
+		`, p.Name, p.Term, p.Price, p.DiningDollars, p.MealSwipes, p.SwipeReset,
+			p.CloseoutRule, p.RolloverCap, p.ConversionRate, p.IsDefault, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const mealPlanColumns = `id, name, term, price, dining_dollars, meal_swipes, swipe_reset,
+	closeout_rule, rollover_cap, conversion_rate, is_default, active, created_at`
+
+func scanMealPlan(row interface{ Scan(...interface{}) error }) (*MealPlan, error) {
+	var p MealPlan
+	err := row.Scan(&p.ID, &p.Name, &p.Term, &p.Price, &p.DiningDollars, &p.MealSwipes, &p.SwipeReset,
+		&p.CloseoutRule, &p.RolloverCap, &p.ConversionRate, &p.IsDefault, &p.Active, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (db *DB) GetMealPlans(activeOnly bool) ([]MealPlan, error) {
+	query := `SELECT ` + mealPlanColumns + ` FROM meal_plans`
+	if activeOnly {
+		query += ` WHERE active = 1`
+	}
+	query += ` ORDER BY term, price`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting meal plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []MealPlan
+	for rows.Next() {
+		p, err := scanMealPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning meal plan: %w", err)
+		}
+		plans = append(plans, *p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating meal plans: %w", err)
+	}
+
+	return plans, nil
+}
+
+func (db *DB) GetMealPlanByID(id int64) (*MealPlan, error) {
+	p, err := scanMealPlan(db.QueryRow(`SELECT `+mealPlanColumns+` FROM meal_plans WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting meal plan: %w", err)
+	}
+	return p, nil
+}
+
+func (db *DB) GetDefaultMealPlan(termKind string) (*MealPlan, error) {
+	p, err := scanMealPlan(db.QueryRow(`
+		SELECT `+mealPlanColumns+`
+		FROM meal_plans
+		WHERE term = ? AND is_default = 1 AND active = 1
+		ORDER BY id LIMIT 1
+	`, termKind))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoDefaultMealPlan
+		}
+		return nil, fmt.Errorf("error getting default meal plan: %w", err)
+	}
+	return p, nil
+}
+
+func (db *DB) CreateMealPlan(plan *MealPlan) (*MealPlan, error) {
+	if plan.Term != TermKindSemester && plan.Term != TermKindSummer {
+		return nil, fmt.Errorf("invalid term %q", plan.Term)
+	}
+	if plan.SwipeReset == "" {
+		plan.SwipeReset = SwipeResetTerm
+	}
+	if plan.CloseoutRule == "" {
+		plan.CloseoutRule = CloseoutForfeit
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if plan.IsDefault {
+		if _, err := dbTx.Exec(`UPDATE meal_plans SET is_default = 0 WHERE term = ?`, plan.Term); err != nil {
+			return nil, fmt.Errorf("error clearing default meal plan: %w", err)
+		}
+	}
+
+	var id int64
+	err = dbTx.QueryRow(`
+		INSERT INTO meal_plans (name, term, price, dining_dollars, meal_swipes, swipe_reset,
+			closeout_rule, rollover_cap, conversion_rate, is_default, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)
+		RETURNING id
+	`, plan.Name, plan.Term, plan.Price, plan.DiningDollars, plan.MealSwipes, plan.SwipeReset,
+		plan.CloseoutRule, plan.RolloverCap, plan.ConversionRate, plan.IsDefault, time.Now()).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("error creating meal plan: %w", err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return db.GetMealPlanByID(id)
+}
+
+func DefaultWeeklyBudget(plan *MealPlan, term *Term) float64 {
+	return roundCents(plan.DiningDollars / float64(term.Weeks()))
+}
+
+func (db *DB) GetUserMealPlan(userID, termID int64) (*UserMealPlan, error) {
+	var ump UserMealPlan
+	var effectiveTo sql.NullTime
+	var planID int64
+	err := db.QueryRow(`
+		SELECT id, user_id, term_id, meal_plan_id, effective_from, effective_to
+		FROM user_meal_plans
+		WHERE user_id = ? AND term_id = ? AND effective_to IS NULL
+		ORDER BY effective_from DESC LIMIT 1
+	`, userID, termID).Scan(&ump.ID, &ump.UserID, &ump.Term.ID, &planID, &ump.EffectiveFrom, &effectiveTo)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting user meal plan: %w", err)
+	}
+	if effectiveTo.Valid {
+		ump.EffectiveTo = &effectiveTo.Time
+	}
+
+	term, err := db.GetTermByID(termID)
+	if err != nil || term == nil {
+		return nil, fmt.Errorf("error getting term for meal plan: %v", err)
+	}
+	ump.Term = *term
+
+	plan, err := db.GetMealPlanByID(planID)
+	if err != nil || plan == nil {
+		return nil, fmt.Errorf("error getting meal plan: %v", err)
+	}
+	ump.MealPlan = *plan
+
+	return &ump, nil
+}
+
+func (db *DB) ChangeMealPlan(userID, planID int64, at time.Time, createdBy *int64) (*UserMealPlan, *BalanceAdjustment, error) {
+	term, err := db.EnsureTerm(at)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := db.GetMealPlanByID(planID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if plan == nil || !plan.Active || plan.Term != term.Kind {
+		return nil, nil, ErrMealPlanUnavailable
+	}
+
+	current, err := db.GetUserMealPlan(userID, term.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var previous *MealPlan
+	if current != nil {
+		previous = &current.MealPlan
+	} else if previous, err = db.GetDefaultMealPlan(term.Kind); err != nil {
+		return nil, nil, err
+	}
+
+	if previous.ID == plan.ID {
+		return nil, nil, ErrSameMealPlan
+	}
+
+	balance, err := db.GetUserBalance(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if current != nil {
+		_, err = dbTx.Exec(`UPDATE user_meal_plans SET effective_to = ? WHERE id = ?`, at, current.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error ending meal plan: %w", err)
+		}
+	}
+
+	_, err = dbTx.Exec(`
+		INSERT INTO user_meal_plans (user_id, term_id, meal_plan_id, effective_from)
+		VALUES (?, ?, ?, ?)
+	`, userID, term.ID, plan.ID, at)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error assigning meal plan: %w", err)
+	}
+
+	var swipes int
+	switch {
+	case plan.SwipeReset != previous.SwipeReset && plan.SwipeReset == SwipeResetTerm:
+		swipes = int(math.Round(float64(plan.MealSwipes) * term.RemainingFraction(at)))
+	case plan.SwipeReset != previous.SwipeReset:
+		swipes = plan.MealSwipes
+	case plan.SwipeReset == SwipeResetTerm:
+		swipes = balance.SwipesRemaining + int(math.Round(float64(plan.MealSwipes-previous.MealSwipes)*term.RemainingFraction(at)))
+	default:
+		swipes = balance.SwipesRemaining + plan.MealSwipes - previous.MealSwipes
+	}
+	if swipes < 0 {
+		swipes = 0
+	}
+	if err := setSwipeAllowance(dbTx, userID, plan, term, swipes, at); err != nil {
+		return nil, nil, err
+	}
+
+	var adj *BalanceAdjustment
+	amount := roundCents((plan.DiningDollars - previous.DiningDollars) * term.RemainingFraction(at))
+	if amount != 0 {
+		adj = &BalanceAdjustment{
+			UserID:    userID,
+			Kind:      AdjustmentPlanChange,
+			Amount:    amount,
+			Reason:    fmt.Sprintf("Prorated change from %s to %s for %s", previous.Name, plan.Name, term.Name),
+			TermID:    &term.ID,
+			CreatedBy: createdBy,
+			CreatedAt: at,
+		}
+		if err := db.applyAdjustment(dbTx, adj); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	ump, err := db.GetUserMealPlan(userID, term.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ump, adj, nil
+}
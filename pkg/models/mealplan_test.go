@@ -0,0 +1,79 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestChangeMealPlanSwipes(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	term, err := db.EnsureTerm(now)
+	if err != nil {
+		t.Fatalf("EnsureTerm: %v", err)
+	}
+	fraction := term.RemainingFraction(now)
+	prorate := func(swipes int) int {
+		return int(math.Round(float64(swipes) * fraction))
+	}
+
+	plans := map[string]*MealPlan{}
+	for _, p := range []MealPlan{
+		{Name: "Weekly 14", MealSwipes: 14, SwipeReset: SwipeResetWeekly},
+		{Name: "Weekly 10", MealSwipes: 10, SwipeReset: SwipeResetWeekly},
+		{Name: "Term 200", MealSwipes: 200, SwipeReset: SwipeResetTerm},
+		{Name: "Term 120", MealSwipes: 120, SwipeReset: SwipeResetTerm},
+		{Name: "Dollars only"},
+	} {
+		p.Term = term.Kind
+		p.DiningDollars = 500
+		p.CloseoutRule = CloseoutForfeit
+		created, err := db.CreateMealPlan(&p)
+		if err != nil {
+			t.Fatalf("CreateMealPlan %s: %v", p.Name, err)
+		}
+		plans[p.Name] = created
+	}
+
+	tests := []struct {
+		name      string
+		from, to  string
+		remaining int
+		want      int
+	}{
+		{"term to weekly", "Term 200", "Weekly 14", 180, 14},
+		{"weekly to term", "Weekly 14", "Term 200", 9, prorate(200)},
+		{"weekly to weekly", "Weekly 14", "Weekly 10", 9, 5},
+		{"weekly to smaller weekly clamps", "Weekly 14", "Weekly 10", 2, 0},
+		{"term to term", "Term 200", "Term 120", 150, 150 - prorate(80)},
+		{"term to larger term", "Term 120", "Term 200", 40, 40 + prorate(80)},
+		{"weekly to dollars only", "Weekly 14", "Dollars only", 9, 0},
+		{"dollars only to weekly", "Dollars only", "Weekly 10", 0, 10},
+	}
+
+	for i, tt := range tests {
+		user := newTestUser(t, db, "6100000"+string(rune('0'+i)))
+		if _, _, err := db.ChangeMealPlan(user.ID, plans[tt.from].ID, now, nil); err != nil {
+			t.Fatalf("%s: ChangeMealPlan to %s: %v", tt.name, tt.from, err)
+		}
+		if _, err := db.Exec(`UPDATE balances SET swipes_remaining = ? WHERE user_id = ?`, tt.remaining, user.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := db.ChangeMealPlan(user.ID, plans[tt.to].ID, now, nil); err != nil {
+			t.Fatalf("%s: ChangeMealPlan to %s: %v", tt.name, tt.to, err)
+		}
+		balance, err := db.GetUserBalance(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserBalance: %v", err)
+		}
+		to := plans[tt.to]
+		if balance.SwipesRemaining != tt.want {
+			t.Errorf("%s: swipes remaining = %d, want %d", tt.name, balance.SwipesRemaining, tt.want)
+		}
+		if balance.SwipeAllowance != to.MealSwipes || balance.SwipeReset != to.SwipeReset {
+			t.Errorf("%s: allowance %d/%s, want %d/%s", tt.name, balance.SwipeAllowance, balance.SwipeReset, to.MealSwipes, to.SwipeReset)
+		}
+	}
+}
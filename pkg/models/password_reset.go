@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+func (db *DB) CreatePasswordResetToken(userID int64, tokenHash, ipAddress string, expiresAt time.Time) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	now := time.Now()
+	_, err = dbTx.Exec(`
+		UPDATE password_reset_tokens SET used_at = ?
+		WHERE user_id = ? AND used_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return fmt.Errorf("error expiring reset tokens: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		INSERT INTO password_reset_tokens (user_id, token_hash, ip_address, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, tokenHash, ipAddress, now, expiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating reset token: %w", err)
+	}
+
+	err = db.recordChange(dbTx, "password.reset_requested", userID, nil, map[string]interface{}{"expires_at": expiresAt})
+	if err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) LastPasswordResetRequest(userID int64) (time.Time, error) {
+	var last time.Time
+	err := db.QueryRow(`
+		SELECT created_at FROM password_reset_tokens
+		WHERE user_id = ?
+		ORDER BY created_at DESC LIMIT 1
+	`, userID).Scan(&last)
+	if err != nil && err != sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("error getting reset tokens: %w", err)
+	}
+	return last, nil
+}
+
+func (db *DB) ResetPassword(tokenHash, password string, at time.Time) (*User, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing password: %w", err)
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	var tokenID, userID int64
+	err = dbTx.QueryRow(`
+		SELECT id, user_id FROM password_reset_tokens
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+	`, tokenHash, at).Scan(&tokenID, &userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting reset token: %w", err)
+	}
+
+	if _, err = dbTx.Exec(`UPDATE password_reset_tokens SET used_at = ? WHERE id = ?`, at, tokenID); err != nil {
+		return nil, fmt.Errorf("error using reset token: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?
+	`, string(passwordHash), at, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating password: %w", err)
+	}
+
+	result, err := dbTx.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE user_id = ? AND revoked_at IS NULL
+	`, at, SessionRevokedPassword, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking sessions: %w", err)
+	}
+	revoked, _ := result.RowsAffected()
+
+	err = db.recordChange(dbTx, "password.reset", userID, nil, map[string]interface{}{"sessions_revoked": revoked})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return db.GetUserByID(userID)
+}
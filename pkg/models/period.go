@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+func WeekStart(t time.Time) time.Time {
+	t = t.In(time.Local)
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.Local)
+}
+
+func WeekBounds(t time.Time) (time.Time, time.Time) {
+	start := WeekStart(t)
+	return start, start.AddDate(0, 0, 7)
+}
+
+func DayBounds(t time.Time) (time.Time, time.Time) {
+	t = t.In(time.Local)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const DefaultRole = "student"
+
+func (db *DB) GetUserRoles(userID int64) ([]string, error) {
+	rows, err := db.Query(`SELECT role FROM user_roles WHERE user_id = ? ORDER BY role`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("error scanning role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (db *DB) GrantUserRole(userID int64, role string) (bool, error) {
+	result, err := db.Exec(`
+		INSERT OR IGNORE INTO user_roles (user_id, role, granted_at)
+		VALUES (?, ?, ?)
+	`, userID, role, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("error granting role: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (db *DB) SetUserRoles(userID int64, roles []string) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if _, err := dbTx.Exec(`DELETE FROM user_roles WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("error clearing roles: %w", err)
+	}
+
+	now := time.Now()
+	for _, role := range roles {
+		_, err := dbTx.Exec(`
+			INSERT OR IGNORE INTO user_roles (user_id, role, granted_at)
+			VALUES (?, ?, ?)
+		`, userID, role, now)
+		if err != nil {
+			return fmt.Errorf("error granting role: %w", err)
+		}
+	}
+
+	_, err = dbTx.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE user_id = ? AND revoked_at IS NULL
+	`, now, SessionRevokedRolesChanged, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
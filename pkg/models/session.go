@@ -0,0 +1,269 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	SessionRevokedLogout       = "logout"
+	SessionRevokedLogoutAll    = "logout_all"
+	SessionRevokedRefreshReuse = "refresh_reuse"
+	SessionRevokedByUser       = "revoked_by_user"
+	SessionRevokedRolesChanged = "roles_changed"
+	SessionRevokedLocked       = "account_locked"
+	SessionRevokedPassword     = "password_reset"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+const sessionColumns = `id, public_id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason`
+
+func scanSession(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*Session, error) {
+	var s Session
+	var revokedAt sql.NullTime
+	dest := append([]interface{}{&s.ID, &s.PublicID, &s.UserID, &s.UserAgent, &s.IPAddress,
+		&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &revokedAt, &s.RevokedReason}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	return &s, nil
+}
+
+func (s *Session) Active(at time.Time) bool {
+	return s.RevokedAt == nil && at.Before(s.ExpiresAt)
+}
+
+func generateSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "sess_" + hex.EncodeToString(b), nil
+}
+
+func (db *DB) CreateSession(userID int64, userAgent, ipAddress, tokenHash string, expiresAt time.Time) (*Session, error) {
+	publicID, err := generateSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("error generating session id: %w", err)
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	now := time.Now()
+	var sessionID int64
+	err = dbTx.QueryRow(`
+		INSERT INTO sessions (public_id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, publicID, userID, userAgent, ipAddress, now, now, expiresAt).Scan(&sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		INSERT INTO refresh_tokens (session_id, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, sessionID, tokenHash, now, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return db.GetSessionByPublicID(publicID)
+}
+
+func (db *DB) GetSessionByPublicID(publicID string) (*Session, error) {
+	s, err := scanSession(db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE public_id = ?`, publicID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting session: %w", err)
+	}
+	return s, nil
+}
+
+func (db *DB) ListActiveSessions(userID int64, at time.Time) ([]Session, error) {
+	rows, err := db.Query(`
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC
+	`, userID, at)
+	if err != nil {
+		return nil, fmt.Errorf("error getting sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning session: %w", err)
+		}
+		sessions = append(sessions, *s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (db *DB) TouchSession(sessionID int64, at time.Time) error {
+	_, err := db.Exec(`UPDATE sessions SET last_used_at = ? WHERE id = ? AND last_used_at < ?`, at, sessionID, at)
+	if err != nil {
+		return fmt.Errorf("error updating session: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) RevokeUserSession(userID int64, publicID, reason string) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE user_id = ? AND public_id = ? AND revoked_at IS NULL
+	`, time.Now(), reason, userID, publicID)
+	if err != nil {
+		return false, fmt.Errorf("error revoking session: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (db *DB) RotateRefreshToken(oldHash, newHash string, expiresAt, now time.Time) (*Session, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	var tokenID int64
+	var tokenExpiresAt time.Time
+	var usedAt sql.NullTime
+	session, err := scanSession(dbTx.QueryRow(`
+		SELECT s.id, s.public_id, s.user_id, s.user_agent, s.ip_address, s.created_at, s.last_used_at,
+			s.expires_at, s.revoked_at, s.revoked_reason, t.id, t.expires_at, t.used_at
+		FROM refresh_tokens t
+		JOIN sessions s ON s.id = t.session_id
+		WHERE t.token_hash = ?
+	`, oldHash), &tokenID, &tokenExpiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting refresh token: %w", err)
+	}
+
+	if !session.Active(now) || !now.Before(tokenExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	reused := usedAt.Valid
+	if !reused {
+		result, err := dbTx.Exec(`
+			UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL
+		`, now, tokenID)
+		if err != nil {
+			return nil, fmt.Errorf("error consuming refresh token: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		reused = n == 0
+	}
+
+	if reused {
+		if err := revokeSession(dbTx, session.ID, SessionRevokedRefreshReuse, now); err != nil {
+			return nil, err
+		}
+		if err = dbTx.Commit(); err != nil {
+			return nil, fmt.Errorf("error committing transaction: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	_, err = dbTx.Exec(`
+		INSERT INTO refresh_tokens (session_id, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, session.ID, newHash, now, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		UPDATE sessions SET last_used_at = ?, expires_at = ? WHERE id = ?
+	`, now, expiresAt, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating session: %w", err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	session.LastUsedAt = now
+	session.ExpiresAt = expiresAt
+	return session, nil
+}
+
+func revokeSession(dbTx *sql.Tx, sessionID int64, reason string, at time.Time) error {
+	_, err := dbTx.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE id = ? AND revoked_at IS NULL
+	`, at, reason, sessionID)
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) RevokeSession(publicID, reason string) error {
+	_, err := db.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE public_id = ? AND revoked_at IS NULL
+	`, time.Now(), reason, publicID)
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) RevokeSessionByRefreshToken(tokenHash, reason string) error {
+	_, err := db.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE id = (SELECT session_id FROM refresh_tokens WHERE token_hash = ?) AND revoked_at IS NULL
+	`, time.Now(), reason, tokenHash)
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) RevokeUserSessions(userID int64, reason string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE user_id = ? AND revoked_at IS NULL
+	`, time.Now(), reason, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error revoking sessions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
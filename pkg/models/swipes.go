@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	AssetDollars = "dollars"
+	AssetSwipes  = "swipes"
+
+	SwipeResetWeekly = "weekly"
+	SwipeResetTerm   = "term"
+)
+
+var (
+	ErrInsufficientSwipes = errors.New("not enough meal swipes remaining")
+	ErrWeeklySwipeLimit   = errors.New("weekly swipe limit exceeded")
+)
+
+func nextSwipeReset(reset string, at time.Time, term *Term) time.Time {
+	if reset == SwipeResetWeekly {
+		_, end := WeekBounds(at)
+		return end
+	}
+	return term.EndsAt
+}
+
+func setSwipeAllowance(dbTx *sql.Tx, userID int64, plan *MealPlan, term *Term, remaining int, at time.Time) error {
+	_, err := dbTx.Exec(`
+		UPDATE balances
+		SET swipes_remaining = ?, swipe_allowance = ?, swipe_reset = ?, swipes_reset_at = ?, updated_at = ?
+		WHERE user_id = ?
+	`, remaining, plan.MealSwipes, plan.SwipeReset, nextSwipeReset(plan.SwipeReset, at, term), time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("error updating swipe balance: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) ResetSwipesIfDue(userID int64, at time.Time) error {
+	balance, err := db.GetUserBalance(userID)
+	if err != nil {
+		return err
+	}
+
+	if balance.SwipeAllowance == 0 || balance.SwipesResetAt == nil || at.Before(*balance.SwipesResetAt) {
+		return nil
+	}
+
+	term, err := db.EnsureTerm(at)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE balances
+		SET swipes_remaining = swipe_allowance, swipes_reset_at = ?, updated_at = ?
+		WHERE user_id = ? AND swipes_reset_at = ?
+	`, nextSwipeReset(balance.SwipeReset, at, term), time.Now(), userID, *balance.SwipesResetAt)
+	if err != nil {
+		return fmt.Errorf("error resetting swipes: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetSwipesUsedBetween(userID int64, start, end time.Time) (int, error) {
+	return swipesUsedBetween(db, userID, start, end)
+}
+
+func swipesUsedBetween(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, userID int64, start, end time.Time) (int, error) {
+	var used float64
+	err := q.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM transactions
+		WHERE user_id = ? AND asset = ? AND transaction_date >= ? AND transaction_date < ?
+	`, userID, AssetSwipes, start, end).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("error getting swipe usage: %w", err)
+	}
+	return int(used), nil
+}
+
+func (db *DB) UseSwipes(userID int64, count int, location, description string, weeklyLimit int) (*Transaction, error) {
+	now := time.Now()
+	if err := db.CheckTermOpen(now); err != nil {
+		return nil, err
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if weeklyLimit > 0 {
+		weekStart, weekEnd := WeekBounds(now)
+		used, err := swipesUsedBetween(dbTx, userID, weekStart, weekEnd)
+		if err != nil {
+			return nil, err
+		}
+		if used+count > weeklyLimit {
+			return nil, ErrWeeklySwipeLimit
+		}
+	}
+
+	before, err := balanceState(dbTx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dbTx.Exec(`
+		UPDATE balances
+		SET swipes_remaining = swipes_remaining - ?, updated_at = ?
+		WHERE user_id = ? AND swipes_remaining >= ?
+	`, count, time.Now(), userID, count)
+	if err != nil {
+		return nil, fmt.Errorf("error updating swipe balance: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrInsufficientSwipes
+	}
+
+	tx := &Transaction{
+		UserID:          userID,
+		Amount:          float64(count),
+		Location:        location,
+		Description:     description,
+		Asset:           AssetSwipes,
+		TransactionDate: time.Now(),
+	}
+
+	err = dbTx.QueryRow(`
+		INSERT INTO transactions (user_id, amount, location, description, asset, transaction_date)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, tx.UserID, tx.Amount, tx.Location, tx.Description, tx.Asset, tx.TransactionDate).Scan(&tx.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error recording transaction: %w", err)
+	}
+
+	if err = db.recordTransaction(dbTx, tx, before); err != nil {
+		return nil, err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return tx, nil
+}
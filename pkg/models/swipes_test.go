@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUseSwipesEnforcesWeeklyLimitConcurrently(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "61000100")
+	if _, err := db.Exec(`UPDATE balances SET swipes_remaining = 20, swipe_allowance = 20, swipe_reset = ? WHERE user_id = ?`, SwipeResetWeekly, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	const limit = 3
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		limited   int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.UseSwipes(user.ID, 1, "Market Cafe", "Lunch", limit)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, ErrWeeklySwipeLimit):
+				limited++
+			default:
+				t.Errorf("UseSwipes: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != limit || limited != 10-limit {
+		t.Fatalf("succeeded %d, limited %d; want %d and %d", succeeded, limited, limit, 10-limit)
+	}
+	weekStart, weekEnd := WeekBounds(time.Now())
+	used, err := db.GetSwipesUsedBetween(user.ID, weekStart, weekEnd)
+	if err != nil {
+		t.Fatalf("GetSwipesUsedBetween: %v", err)
+	}
+	if used != limit {
+		t.Fatalf("swipes used this week = %d, want %d", used, limit)
+	}
+
+	if _, err := db.UseSwipes(user.ID, 2, "Market Cafe", "Dinner", 0); err != nil {
+		t.Fatalf("UseSwipes without a limit: %v", err)
+	}
+}
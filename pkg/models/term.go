@@ -0,0 +1,233 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrTermClosed = errors.New("term is closed")
+
+const (
+	TermKindSemester = "semester"
+	TermKindSummer   = "summer"
+)
+
+func termBoundsFor(t time.Time) (name, kind string, start, end time.Time) {
+	t = t.In(time.Local)
+	y := t.Year()
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	switch {
+	case t.Before(date(y, time.May, 16)):
+		return fmt.Sprintf("Spring %d", y), TermKindSemester, date(y, time.January, 1), date(y, time.May, 16)
+	case t.Before(date(y, time.August, 15)):
+		return fmt.Sprintf("Summer %d", y), TermKindSummer, date(y, time.May, 16), date(y, time.August, 15)
+	default:
+		return fmt.Sprintf("Fall %d", y), TermKindSemester, date(y, time.August, 15), date(y+1, time.January, 1)
+	}
+}
+
+func (t *Term) Weeks() int {
+	days := int(t.EndsAt.Sub(t.StartsAt).Hours() / 24)
+	weeks := (days + 6) / 7
+	if weeks < 1 {
+		weeks = 1
+	}
+	return weeks
+}
+
+func (t *Term) RemainingFraction(at time.Time) float64 {
+	total := t.EndsAt.Sub(t.StartsAt)
+	if total <= 0 || !at.Before(t.EndsAt) {
+		return 0
+	}
+	if at.Before(t.StartsAt) {
+		return 1
+	}
+	return float64(t.EndsAt.Sub(at)) / float64(total)
+}
+
+const termColumns = `id, name, kind, starts_at, ends_at, closed_at`
+
+func scanTerm(row interface{ Scan(...interface{}) error }) (*Term, error) {
+	var t Term
+	var closedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.Name, &t.Kind, &t.StartsAt, &t.EndsAt, &closedAt); err != nil {
+		return nil, err
+	}
+	if closedAt.Valid {
+		t.ClosedAt = &closedAt.Time
+	}
+	return &t, nil
+}
+
+func (db *DB) GetTermAt(at time.Time) (*Term, error) {
+	t, err := scanTerm(db.QueryRow(`
+		SELECT `+termColumns+`
+		FROM terms
+		WHERE starts_at <= ? AND ends_at > ?
+		ORDER BY starts_at DESC LIMIT 1
+	`, at, at))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting term: %w", err)
+	}
+	return t, nil
+}
+
+func (db *DB) GetTermByID(id int64) (*Term, error) {
+	t, err := scanTerm(db.QueryRow(`
+		SELECT `+termColumns+`
+		FROM terms
+		WHERE id = ?
+	`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting term: %w", err)
+	}
+	return t, nil
+}
+
+func (db *DB) EnsureTerm(at time.Time) (*Term, error) {
+	term, err := db.GetTermAt(at)
+	if err != nil || term != nil {
+		return term, err
+	}
+
+	name, kind, start, end := termBoundsFor(at)
+	_, err = db.Exec(`
+		INSERT OR IGNORE INTO terms (name, kind, starts_at, ends_at)
+		VALUES (?, ?, ?, ?)
+	`, name, kind, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error creating term: %w", err)
+	}
+
+	return db.GetTermAt(at)
+}
+
+func (db *DB) GetTermByName(name string) (*Term, error) {
+	t, err := scanTerm(db.QueryRow(`SELECT `+termColumns+` FROM terms WHERE name = ?`, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting term: %w", err)
+	}
+	return t, nil
+}
+
+func (db *DB) CheckTermOpen(at time.Time) error {
+	var closed int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM terms
+		WHERE starts_at <= ? AND ends_at > ? AND closed_at IS NOT NULL
+	`, at, at).Scan(&closed)
+	if err != nil {
+		return fmt.Errorf("error checking term: %w", err)
+	}
+	if closed > 0 {
+		return ErrTermClosed
+	}
+	return nil
+}
+
+type CloseoutFunc func(userID int64, balance float64, swipes int) []BalanceAdjustment
+
+func (db *DB) GetCloseoutBalance(userID int64, term *Term) (float64, int, error) {
+	return closeoutBalance(db, userID, term)
+}
+
+func closeoutBalance(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, userID int64, term *Term) (float64, int, error) {
+	balance, err := balanceAt(q, userID, term.EndsAt)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var swipes int
+	var resetAt sql.NullTime
+	err = q.QueryRow(`
+		SELECT swipes_remaining, swipes_reset_at FROM balances WHERE user_id = ?
+	`, userID).Scan(&swipes, &resetAt)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading balance: %w", err)
+	}
+	if !resetAt.Valid || resetAt.Time.After(term.EndsAt) {
+		swipes = 0
+	}
+
+	return balance, swipes, nil
+}
+
+func (db *DB) CloseTerm(termID int64, userIDs []int64, at time.Time, settle CloseoutFunc) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	result, err := dbTx.Exec(`UPDATE terms SET closed_at = ? WHERE id = ? AND closed_at IS NULL`, at, termID)
+	if err != nil {
+		return fmt.Errorf("error closing term: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTermClosed
+	}
+
+	term, err := scanTerm(dbTx.QueryRow(`SELECT `+termColumns+` FROM terms WHERE id = ?`, termID))
+	if err != nil {
+		return fmt.Errorf("error getting term: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		balance, swipes, err := closeoutBalance(dbTx, userID, term)
+		if err != nil {
+			return err
+		}
+
+		adjustments := settle(userID, balance, swipes)
+		for i := range adjustments {
+			if err := db.applyAdjustment(dbTx, &adjustments[i]); err != nil {
+				return err
+			}
+		}
+
+		if swipes > 0 {
+			before, err := balanceState(dbTx, userID)
+			if err != nil {
+				return err
+			}
+			_, err = dbTx.Exec(`
+				UPDATE balances SET swipes_remaining = swipes_remaining - ?, updated_at = ?
+				WHERE user_id = ?
+			`, swipes, at, userID)
+			if err != nil {
+				return fmt.Errorf("error clearing swipes: %w", err)
+			}
+			after, err := balanceState(dbTx, userID)
+			if err != nil {
+				return err
+			}
+			after["term_id"] = termID
+			if err := db.recordChange(dbTx, "closeout.swipes_cleared", userID, before, after); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotPending     = errors.New("two-factor enrollment has not been started")
+	ErrChallengeInvalid   = errors.New("login challenge is invalid or expired")
+)
+
+type TOTPEnrollment struct {
+	UserID       int64
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+}
+
+func (db *DB) GetTOTP(userID int64) (*TOTPEnrollment, error) {
+	var e TOTPEnrollment
+	var enabledAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT user_id, secret, enabled_at, last_used_step FROM user_totp WHERE user_id = ?
+	`, userID).Scan(&e.UserID, &e.Secret, &enabledAt, &e.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting two-factor enrollment: %w", err)
+	}
+	if enabledAt.Valid {
+		e.EnabledAt = &enabledAt.Time
+	}
+	return &e, nil
+}
+
+func (db *DB) StartTOTPEnrollment(userID int64, secret string) error {
+	result, err := db.Exec(`
+		INSERT INTO user_totp (user_id, secret, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, created_at = excluded.created_at
+		WHERE user_totp.enabled_at IS NULL
+	`, userID, secret, time.Now())
+	if err != nil {
+		return fmt.Errorf("error starting two-factor enrollment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+func (db *DB) EnableTOTP(userID, step int64, recoveryCodeHashes []string, at time.Time) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	result, err := dbTx.Exec(`
+		UPDATE user_totp SET enabled_at = ?, last_used_step = ?
+		WHERE user_id = ? AND enabled_at IS NULL
+	`, at, step, userID)
+	if err != nil {
+		return fmt.Errorf("error enabling two-factor authentication: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTOTPNotPending
+	}
+
+	if err = replaceRecoveryCodes(dbTx, userID, recoveryCodeHashes, at); err != nil {
+		return err
+	}
+
+	err = db.recordChange(dbTx, "2fa.enable", userID, nil, map[string]interface{}{"recovery_codes": len(recoveryCodeHashes)})
+	if err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) RegenerateRecoveryCodes(userID int64, recoveryCodeHashes []string, at time.Time) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if err = replaceRecoveryCodes(dbTx, userID, recoveryCodeHashes, at); err != nil {
+		return err
+	}
+
+	err = db.recordChange(dbTx, "2fa.recovery_codes", userID, nil, map[string]interface{}{"recovery_codes": len(recoveryCodeHashes)})
+	if err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(dbTx *sql.Tx, userID int64, hashes []string, at time.Time) error {
+	if _, err := dbTx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("error clearing recovery codes: %w", err)
+	}
+	for _, hash := range hashes {
+		_, err := dbTx.Exec(`
+			INSERT INTO totp_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)
+		`, userID, hash, at)
+		if err != nil {
+			return fmt.Errorf("error creating recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+func (db *DB) UseTOTPStep(userID, step int64) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE user_totp SET last_used_step = ?
+		WHERE user_id = ? AND enabled_at IS NOT NULL AND last_used_step < ?
+	`, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("error using two-factor code: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (db *DB) UseRecoveryCode(userID int64, codeHash string, at time.Time) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE totp_recovery_codes SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`, at, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("error using recovery code: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (db *DB) RemainingRecoveryCodes(userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM totp_recovery_codes WHERE user_id = ? AND used_at IS NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func (db *DB) DisableTOTP(userID int64) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if _, err = dbTx.Exec(`DELETE FROM user_totp WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("error disabling two-factor authentication: %w", err)
+	}
+	if _, err = dbTx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("error clearing recovery codes: %w", err)
+	}
+
+	if err = db.recordChange(dbTx, "2fa.disable", userID, nil, nil); err != nil {
+		return err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) CreateLoginChallenge(userID int64, tokenHash, ipAddress string, expiresAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO login_challenges (user_id, token_hash, ip_address, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, tokenHash, ipAddress, time.Now(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating login challenge: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) GetLoginChallenge(tokenHash string, at time.Time) (int64, error) {
+	var userID int64
+	err := db.QueryRow(`
+		SELECT user_id FROM login_challenges
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+	`, tokenHash, at).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrChallengeInvalid
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error getting login challenge: %w", err)
+	}
+	return userID, nil
+}
+
+func (db *DB) CompleteLoginChallenge(tokenHash string, at time.Time) error {
+	result, err := db.Exec(`
+		UPDATE login_challenges SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+	`, at, tokenHash, at)
+	if err != nil {
+		return fmt.Errorf("error completing login challenge: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrChallengeInvalid
+	}
+	return nil
+}
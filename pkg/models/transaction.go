@@ -1,7 +1,13 @@
 package models
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,7 +26,7 @@ func (db *DB) GetUserTransactions(userID int64, limit, offset int) ([]Transactio
 	}
 
 	rows, err := db.Query(`
-		SELECT id, user_id, amount, location, description, transaction_date
+		SELECT id, user_id, amount, location, description, asset, transaction_date
 		FROM transactions
 		WHERE user_id = ?
 		ORDER BY transaction_date DESC
@@ -37,7 +43,7 @@ func (db *DB) GetUserTransactions(userID int64, limit, offset int) ([]Transactio
 		var tx Transaction
 		err := rows.Scan(
 			&tx.ID, &tx.UserID, &tx.Amount, &tx.Location, 
-			&tx.Description, &tx.TransactionDate,
+			&tx.Description, &tx.Asset, &tx.TransactionDate,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("error scanning transaction: %w", err)
@@ -53,6 +59,10 @@ func (db *DB) GetUserTransactions(userID int64, limit, offset int) ([]Transactio
 }
 
 func (db *DB) CreateTransaction(userID int64, amount float64, location, description string) (*Transaction, error) {
+	if err := db.CheckTermOpen(time.Now()); err != nil {
+		return nil, err
+	}
+
 	balance, err := db.GetUserBalance(userID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting balance: %w", err)
@@ -65,6 +75,7 @@ func (db *DB) CreateTransaction(userID int64, amount float64, location, descript
 		Amount:          amount,
 		Location:        location,
 		Description:     description,
+		Asset:           AssetDollars,
 		TransactionDate: time.Now(),
 	}
 
@@ -73,7 +84,7 @@ func (db *DB) CreateTransaction(userID int64, amount float64, location, descript
 	}
 
 	rows, err := db.Query(`
-		SELECT id, user_id, amount, location, description, transaction_date
+		SELECT id, user_id, amount, location, description, asset, transaction_date
 		FROM transactions
 		WHERE user_id = ? AND location = ?
 		ORDER BY transaction_date DESC LIMIT 1
@@ -86,7 +97,7 @@ func (db *DB) CreateTransaction(userID int64, amount float64, location, descript
 	if rows.Next() {
 		err := rows.Scan(
 			&tx.ID, &tx.UserID, &tx.Amount, &tx.Location, 
-			&tx.Description, &tx.TransactionDate,
+			&tx.Description, &tx.Asset, &tx.TransactionDate,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning transaction: %w", err)
@@ -94,4 +105,261 @@ func (db *DB) CreateTransaction(userID int64, amount float64, location, descript
 	}
 
 	return tx, nil
-} 
\ No newline at end of file
+} 
+func (db *DB) GetTransactionsBetween(userID int64, start, end time.Time) ([]Transaction, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, amount, location, description, asset, transaction_date
+		FROM transactions
+		WHERE user_id = ? AND transaction_date >= ? AND transaction_date < ?
+		ORDER BY transaction_date ASC, id ASC
+	`, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var tx Transaction
+		err := rows.Scan(
+			&tx.ID, &tx.UserID, &tx.Amount, &tx.Location,
+			&tx.Description, &tx.Asset, &tx.TransactionDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+func (db *DB) GetTransactionTotalBefore(userID int64, before time.Time) (float64, error) {
+	var total float64
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM transactions
+		WHERE user_id = ? AND asset = ? AND transaction_date < ?
+	`, userID, AssetDollars, before).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("error getting transaction total: %w", err)
+	}
+
+	return total, nil
+}
+
+func (db *DB) GetDollarsSpentBetween(userID int64, start, end time.Time) (float64, error) {
+	var total float64
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM transactions
+		WHERE user_id = ? AND asset = ? AND transaction_date >= ? AND transaction_date < ?
+	`, userID, AssetDollars, start, end).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("error getting transaction total: %w", err)
+	}
+
+	return total, nil
+}
+
+type TransactionFilter struct {
+	Asset     string
+	From      time.Time
+	To        time.Time
+	Location  string
+	MinAmount *float64
+	MaxAmount *float64
+}
+
+func (f TransactionFilter) where(userID int64) (string, []interface{}) {
+	clause := "user_id = ?"
+	args := []interface{}{userID}
+
+	if f.Asset != "" {
+		clause += " AND asset = ?"
+		args = append(args, f.Asset)
+	}
+	if !f.From.IsZero() {
+		clause += " AND transaction_date >= ?"
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		clause += " AND transaction_date < ?"
+		args = append(args, f.To)
+	}
+	if f.Location != "" {
+		clause += " AND location = ?"
+		args = append(args, f.Location)
+	}
+	if f.MinAmount != nil {
+		clause += " AND amount >= ?"
+		args = append(args, *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		clause += " AND amount <= ?"
+		args = append(args, *f.MaxAmount)
+	}
+
+	return clause, args
+}
+
+func (db *DB) EachUserTransaction(ctx context.Context, userID int64, filter TransactionFilter, fn func(*Transaction) error) error {
+	where, args := filter.where(userID)
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_id, amount, location, description, asset, transaction_date
+		FROM transactions
+		WHERE `+where+`
+		ORDER BY transaction_date ASC, id ASC
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("error getting transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var tx Transaction
+	for rows.Next() {
+		err := rows.Scan(
+			&tx.ID, &tx.UserID, &tx.Amount, &tx.Location,
+			&tx.Description, &tx.Asset, &tx.TransactionDate,
+		)
+		if err != nil {
+			return fmt.Errorf("error scanning transaction: %w", err)
+		}
+		if err := fn(&tx); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return nil
+}
+
+func TransactionFingerprint(date time.Time, amount float64, location string) string {
+	key := fmt.Sprintf("%s|%s|%s",
+		date.Format("2006-01-02"),
+		strconv.FormatFloat(amount, 'f', 2, 64),
+		strings.ToLower(strings.TrimSpace(location)),
+	)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+func existingFingerprints(dbTx *sql.Tx, userID int64, transactions []Transaction) (map[string]int, error) {
+	existing := make(map[string]int)
+	if len(transactions) == 0 {
+		return existing, nil
+	}
+
+	start, end := transactions[0].TransactionDate, transactions[0].TransactionDate
+	for _, tx := range transactions {
+		if tx.TransactionDate.Before(start) {
+			start = tx.TransactionDate
+		}
+		if tx.TransactionDate.After(end) {
+			end = tx.TransactionDate
+		}
+	}
+	start, _ = DayBounds(start)
+	_, end = DayBounds(end)
+
+	rows, err := dbTx.Query(`
+		SELECT amount, location, transaction_date
+		FROM transactions
+		WHERE user_id = ? AND asset = ? AND transaction_date >= ? AND transaction_date < ?
+	`, userID, AssetDollars, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tx Transaction
+		if err := rows.Scan(&tx.Amount, &tx.Location, &tx.TransactionDate); err != nil {
+			return nil, fmt.Errorf("error scanning transaction: %w", err)
+		}
+		existing[TransactionFingerprint(tx.TransactionDate, tx.Amount, tx.Location)]++
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return existing, nil
+}
+
+func (db *DB) ImportTransactions(userID int64, transactions []Transaction) (float64, []int, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return 0, nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	before, err := balanceState(dbTx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	existing, err := existingFingerprints(dbTx, userID, transactions)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var total float64
+	var imported int
+	var duplicates []int
+	for i, tx := range transactions {
+		fingerprint := TransactionFingerprint(tx.TransactionDate, tx.Amount, tx.Location)
+		if existing[fingerprint] > 0 {
+			existing[fingerprint]--
+			duplicates = append(duplicates, i)
+			continue
+		}
+		if err := db.CheckTermOpen(tx.TransactionDate); err != nil {
+			return 0, nil, err
+		}
+		_, err = dbTx.Exec(`
+			INSERT INTO transactions (user_id, amount, location, description, transaction_date)
+			VALUES (?, ?, ?, ?, ?)
+		`, userID, tx.Amount, tx.Location, tx.Description, tx.TransactionDate)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error recording transaction: %w", err)
+		}
+		total += tx.Amount
+		imported++
+	}
+
+	balance, err := recalculateBalance(dbTx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	after, err := balanceState(dbTx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	after["imported"] = imported
+	after["imported_total"] = roundCents(total)
+	if err = db.recordChange(dbTx, "transaction.import", userID, before, after); err != nil {
+		return 0, nil, err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return balance, duplicates, nil
+}
+
+func (db *DB) recordTransaction(dbTx *sql.Tx, tx *Transaction, before map[string]interface{}) error {
+	after, err := balanceState(dbTx, tx.UserID)
+	if err != nil {
+		return err
+	}
+	after["transaction"] = tx
+	return db.recordChange(dbTx, "transaction.create", tx.UserID, before, after)
+}
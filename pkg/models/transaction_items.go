@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (db *DB) CreateItemizedTransaction(tx *Transaction, items []TransactionItem) (*Transaction, error) {
+	if tx.TransactionDate.IsZero() {
+		tx.TransactionDate = time.Now()
+	}
+	if err := db.CheckTermOpen(tx.TransactionDate); err != nil {
+		return nil, err
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	before, err := balanceState(dbTx, tx.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.Asset = AssetDollars
+	err = dbTx.QueryRow(`
+		INSERT INTO transactions (user_id, amount, location, description, asset, transaction_date)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, tx.UserID, tx.Amount, tx.Location, tx.Description, tx.Asset, tx.TransactionDate).Scan(&tx.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error recording transaction: %w", err)
+	}
+
+	tx.Items = make([]TransactionItem, len(items))
+	for i, item := range items {
+		item.TransactionID = tx.ID
+		err = dbTx.QueryRow(`
+			INSERT INTO transaction_items (transaction_id, menu_item_id, name, quantity, unit_price, discount, tax, total,
+				calories, protein_g, carbs_g, fat_g)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, item.TransactionID, item.MenuItemID, item.Name, item.Quantity, item.UnitPrice,
+			item.Discount, item.Tax, item.Total, item.Nutrition.Calories, item.Nutrition.Protein,
+			item.Nutrition.Carbs, item.Nutrition.Fat).Scan(&item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error recording transaction item: %w", err)
+		}
+		tx.Items[i] = item
+	}
+
+	if _, err := recalculateBalance(dbTx, tx.UserID); err != nil {
+		return nil, err
+	}
+
+	if err = db.recordTransaction(dbTx, tx, before); err != nil {
+		return nil, err
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+func (db *DB) GetTransactionItems(transactionIDs []int64) (map[int64][]TransactionItem, error) {
+	items := make(map[int64][]TransactionItem)
+	if len(transactionIDs) == 0 {
+		return items, nil
+	}
+
+	args := make([]interface{}, len(transactionIDs))
+	for i, id := range transactionIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(transactionIDs)), ",")
+
+	rows, err := db.Query(`
+		SELECT id, transaction_id, menu_item_id, name, quantity, unit_price, discount, tax, total,
+			calories, protein_g, carbs_g, fat_g
+		FROM transaction_items
+		WHERE transaction_id IN (`+placeholders+`)
+		ORDER BY transaction_id, id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting transaction items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item TransactionItem
+		err := rows.Scan(&item.ID, &item.TransactionID, &item.MenuItemID, &item.Name, &item.Quantity,
+			&item.UnitPrice, &item.Discount, &item.Tax, &item.Total, &item.Nutrition.Calories,
+			&item.Nutrition.Protein, &item.Nutrition.Carbs, &item.Nutrition.Fat)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning transaction item: %w", err)
+		}
+		items[item.TransactionID] = append(items[item.TransactionID], item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transaction items: %w", err)
+	}
+
+	return items, nil
+}
+
+func (db *DB) GetMenuItemPurchaseCounts(userID int64, since time.Time) (map[int64]int, error) {
+	rows, err := db.Query(`
+		SELECT i.menu_item_id, SUM(i.quantity)
+		FROM transaction_items i
+		JOIN transactions t ON t.id = i.transaction_id
+		WHERE t.user_id = ? AND t.transaction_date >= ? AND i.menu_item_id IS NOT NULL
+		GROUP BY i.menu_item_id
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("error getting purchase counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var menuItemID int64
+		var count int
+		if err := rows.Scan(&menuItemID, &count); err != nil {
+			return nil, fmt.Errorf("error scanning purchase count: %w", err)
+		}
+		counts[menuItemID] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating purchase counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (db *DB) GetNutritionBetween(userID int64, start, end time.Time) (Nutrition, error) {
+	var n Nutrition
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(i.calories), 0), COALESCE(SUM(i.protein_g), 0),
+			COALESCE(SUM(i.carbs_g), 0), COALESCE(SUM(i.fat_g), 0)
+		FROM transaction_items i
+		JOIN transactions t ON t.id = i.transaction_id
+		WHERE t.user_id = ? AND t.transaction_date >= ? AND t.transaction_date < ?
+	`, userID, start, end).Scan(&n.Calories, &n.Protein, &n.Carbs, &n.Fat)
+	if err != nil {
+		return n, fmt.Errorf("error getting nutrition totals: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestImportTransactionsDedupe(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "61000200")
+	start := currentBalance(t, db, user.ID)
+
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day()-2, 11, 0, 0, 0, time.Local)
+	lunch := Transaction{Amount: 12.34, Location: "Market Cafe", Description: "Lunch", TransactionDate: day}
+	sameLunch := Transaction{Amount: 12.34, Location: " market cafe", Description: "Lunch again", TransactionDate: day.Add(time.Hour)}
+	coffee := Transaction{Amount: 5.00, Location: "Cafe", Description: "Coffee", TransactionDate: day}
+	dinner := Transaction{Amount: 7.77, Location: "Market Cafe", Description: "Dinner", TransactionDate: day.AddDate(0, 0, 1)}
+
+	tests := []struct {
+		name       string
+		batch      []Transaction
+		duplicates []int
+		spent      float64
+	}{
+		{"first import", []Transaction{lunch, sameLunch, coffee}, nil, 29.68},
+		{"reimport with new rows", []Transaction{lunch, sameLunch, coffee, dinner, lunch}, []int{0, 1, 2}, 49.79},
+		{"full reimport", []Transaction{lunch, sameLunch, coffee, dinner, lunch}, []int{0, 1, 2, 3, 4}, 49.79},
+		{"refund is not a duplicate of the purchase", []Transaction{{Amount: -12.34, Location: "Market Cafe", TransactionDate: day}}, nil, 37.45},
+	}
+
+	for _, tt := range tests {
+		balance, duplicates, err := db.ImportTransactions(user.ID, tt.batch)
+		if err != nil {
+			t.Fatalf("%s: ImportTransactions: %v", tt.name, err)
+		}
+		if fmt.Sprint(duplicates) != fmt.Sprint(tt.duplicates) {
+			t.Errorf("%s: duplicates = %v, want %v", tt.name, duplicates, tt.duplicates)
+		}
+		if got := roundCents(start - balance); got != tt.spent {
+			t.Errorf("%s: spent %.2f, want %.2f", tt.name, got, tt.spent)
+		}
+		if got := currentBalance(t, db, user.ID); got != balance {
+			t.Errorf("%s: stored balance %.2f, returned %.2f", tt.name, got, balance)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -14,6 +15,17 @@ func (db *DB) CreateUser(studentID, name, email, password string) (*User, error)
 		return nil, fmt.Errorf("error generating password hash: %w", err)
 	}
 
+	now := time.Now()
+	term, err := db.EnsureTerm(now)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current term: %w", err)
+	}
+
+	plan, err := db.GetDefaultMealPlan(term.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("error getting default meal plan: %w", err)
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("error beginning transaction: %w", err)
@@ -34,7 +46,7 @@ func (db *DB) CreateUser(studentID, name, email, password string) (*User, error)
 	_, err = tx.Exec(`
 		INSERT INTO balances (user_id, starting_balance, current_balance, updated_at)
 		VALUES (?, ?, ?, ?)
-	`, userID, 1500.00, 1500.00, time.Now())
+	`, userID, plan.DiningDollars, plan.DiningDollars, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("error creating balance: %w", err)
 	}
@@ -42,11 +54,28 @@ func (db *DB) CreateUser(studentID, name, email, password string) (*User, error)
 	_, err = tx.Exec(`
 		INSERT INTO budget_settings (user_id, weekly_budget, budget_warnings, strict_budget, transaction_notifications, weekly_reports, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, userID, 100.00, true, false, true, true, time.Now())
+	`, userID, DefaultWeeklyBudget(plan, term), true, false, true, true, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("error creating budget settings: %w", err)
 	}
 
+	_, err = tx.Exec(`
+		INSERT INTO user_meal_plans (user_id, term_id, meal_plan_id, effective_from)
+		VALUES (?, ?, ?, ?)
+	`, userID, term.ID, plan.ID, now)
+	if err != nil {
+		return nil, fmt.Errorf("error assigning meal plan: %w", err)
+	}
+
+	if err = setSwipeAllowance(tx, userID, plan, term, plan.MealSwipes, now); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO user_roles (user_id, role) VALUES (?, ?)`, userID, DefaultRole)
+	if err != nil {
+		return nil, fmt.Errorf("error assigning role: %w", err)
+	}
+
 	if err = tx.Commit(); err != nil {
 		return nil, fmt.Errorf("error committing transaction: %w", err)
 	}
@@ -54,13 +83,31 @@ func (db *DB) CreateUser(studentID, name, email, password string) (*User, error)
 	return db.GetUserByID(userID)
 }
 
-func (db *DB) GetUserByID(id int64) (*User, error) {
+const userColumns = `id, student_id, name, email, password_hash, created_at, updated_at, locked_at, locked_reason, email_verified_at`
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
 	var user User
-	err := db.QueryRow(`
-		SELECT id, student_id, name, email, password_hash, created_at, updated_at
+	var lockedAt, verifiedAt sql.NullTime
+	err := row.Scan(&user.ID, &user.StudentID, &user.Name, &user.Email, &user.PasswordHash,
+		&user.CreatedAt, &user.UpdatedAt, &lockedAt, &user.LockedReason, &verifiedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lockedAt.Valid {
+		user.LockedAt = &lockedAt.Time
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	return &user, nil
+}
+
+func (db *DB) GetUserByID(id int64) (*User, error) {
+	user, err := scanUser(db.QueryRow(`
+		SELECT `+userColumns+`
 		FROM users
 		WHERE id = ?
-	`, id).Scan(&user.ID, &user.StudentID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	`, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -69,16 +116,15 @@ func (db *DB) GetUserByID(id int64) (*User, error) {
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 func (db *DB) GetUserByStudentID(studentID string) (*User, error) {
-	var user User
-	err := db.QueryRow(`
-		SELECT id, student_id, name, email, password_hash, created_at, updated_at
+	user, err := scanUser(db.QueryRow(`
+		SELECT `+userColumns+`
 		FROM users
 		WHERE student_id = ?
-	`, studentID).Scan(&user.ID, &user.StudentID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	`, studentID))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -87,10 +133,125 @@ func (db *DB) GetUserByStudentID(studentID string) (*User, error) {
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+func (db *DB) GetUserByEmail(email string) (*User, error) {
+	user, err := scanUser(db.QueryRow(`
+		SELECT `+userColumns+`
+		FROM users
+		WHERE email = ? COLLATE NOCASE
+	`, email))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	return user, nil
 }
 
 func (db *DB) VerifyPassword(user *User, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	return err == nil
-} 
\ No newline at end of file
+}
+
+func (db *DB) GetAllUsers() ([]User, error) {
+	rows, err := db.Query(`
+		SELECT `+userColumns+`
+		FROM users
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (db *DB) SearchUsers(query string, limit int) ([]User, error) {
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+	rows, err := db.Query(`
+		SELECT `+userColumns+`
+		FROM users
+		WHERE student_id LIKE ? ESCAPE '\' OR name LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\'
+		ORDER BY student_id
+		LIMIT ?
+	`, pattern, pattern, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (db *DB) LockUser(userID int64, reason string, at time.Time) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	_, err = dbTx.Exec(`
+		UPDATE users SET locked_at = ?, locked_reason = ?, updated_at = ?
+		WHERE id = ?
+	`, at, reason, at, userID)
+	if err != nil {
+		return fmt.Errorf("error locking user: %w", err)
+	}
+
+	_, err = dbTx.Exec(`
+		UPDATE sessions SET revoked_at = ?, revoked_reason = ?
+		WHERE user_id = ? AND revoked_at IS NULL
+	`, at, SessionRevokedLocked, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) UnlockUser(userID int64) error {
+	_, err := db.Exec(`
+		UPDATE users SET locked_at = NULL, locked_reason = '', updated_at = ?
+		WHERE id = ?
+	`, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("error unlocking user: %w", err)
+	}
+	return nil
+}
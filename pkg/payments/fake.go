@@ -0,0 +1,71 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	FakeTokenDecline = "tok_decline"
+	FakeTokenPending = "tok_pending"
+)
+
+type FakeProvider struct {
+	mu      sync.Mutex
+	next    int
+	charges map[string]*ChargeResult
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{charges: make(map[string]*ChargeResult)}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Charge(ctx context.Context, req ChargeRequest) (*ChargeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.charges[req.Reference]; ok {
+		copied := *existing
+		return &copied, nil
+	}
+
+	p.next++
+	result := &ChargeResult{
+		ProviderReference: fmt.Sprintf("fake_ch_%06d", p.next),
+		Status:            StatusSettled,
+	}
+
+	switch req.PaymentToken {
+	case FakeTokenDecline:
+		result.Status = StatusFailed
+		result.FailureReason = "card declined"
+	case FakeTokenPending:
+		result.Status = StatusPending
+	}
+
+	p.charges[req.Reference] = result
+	copied := *result
+	return &copied, nil
+}
+
+func (p *FakeProvider) Lookup(ctx context.Context, reference string) (*ChargeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.charges[reference]
+	if !ok {
+		return nil, ErrUnknownCharge
+	}
+
+	if result.Status == StatusPending {
+		result.Status = StatusSettled
+	}
+
+	copied := *result
+	return &copied, nil
+}
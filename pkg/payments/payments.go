@@ -0,0 +1,34 @@
+package payments
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	StatusSettled = "settled"
+	StatusPending = "pending"
+	StatusFailed  = "failed"
+)
+
+var ErrUnknownCharge = errors.New("unknown charge")
+
+type ChargeRequest struct {
+	Reference    string
+	Amount       float64
+	Currency     string
+	Description  string
+	PaymentToken string
+}
+
+type ChargeResult struct {
+	ProviderReference string
+	Status            string
+	FailureReason     string
+}
+
+type Provider interface {
+	Name() string
+	Charge(ctx context.Context, req ChargeRequest) (*ChargeResult, error)
+	Lookup(ctx context.Context, reference string) (*ChargeResult, error)
+}
@@ -0,0 +1,62 @@
+package statements
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"money": formatMoney,
+	"date": func(s *Statement) string {
+		return s.PeriodStart.Format("January 2006")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>FlexiBudget Statement {{.Month}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; margin: 40px; }
+h1 { font-size: 22px; margin-bottom: 4px; }
+.meta { color: #555; margin-bottom: 24px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; }
+.summary td { border: none; padding: 2px 8px; }
+</style>
+</head>
+<body>
+<h1>FlexiBudget Meal Plan Statement</h1>
+<div class="meta">
+{{.Name}} &middot; Student ID {{.StudentID}}<br>
+Statement period: {{date .}}<br>
+Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}
+</div>
+<table class="summary">
+<tr><td>Opening balance</td><td class="num">{{money .OpeningBalance}}</td></tr>
+<tr><td>Deposits</td><td class="num">{{money .TotalDeposits}}</td></tr>
+<tr><td>Adjustments</td><td class="num">{{money .TotalAdjustments}}</td></tr>
+<tr><td>Refunds</td><td class="num">{{money .TotalRefunds}}</td></tr>
+<tr><td>Purchases</td><td class="num">{{money .TotalPurchases}}</td></tr>
+<tr><td><strong>Closing balance</strong></td><td class="num"><strong>{{money .ClosingBalance}}</strong></td></tr>
+{{if .SwipesUsed}}<tr><td>Meal swipes used</td><td class="num">{{.SwipesUsed}}</td></tr>
+{{end}}</table>
+<h2>Activity</h2>
+<table>
+<thead><tr><th>Date</th><th>Type</th><th>Location</th><th>Description</th><th class="num">Amount</th><th class="num">Balance</th></tr></thead>
+<tbody>
+{{range .Entries}}<tr><td>{{.Date.Format "2006-01-02"}}</td><td>{{.Kind}}</td><td>{{.Location}}</td><td>{{.Description}}</td><td class="num">{{money .Amount}}</td><td class="num">{{money .Balance}}</td></tr>
+{{else}}<tr><td colspan="6">No activity this period.</td></tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+func (s *Statement) RenderHTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
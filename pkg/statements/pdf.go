@@ -0,0 +1,207 @@
+package statements
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pageWidth      = 612.0
+	pageHeight     = 792.0
+	pageMargin     = 50.0
+	rowHeight      = 16.0
+	fontRegular    = "F1"
+	fontBold       = "F2"
+	maxLocation    = 22
+	maxDescription = 30
+)
+
+type pdfWriter struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+func (p *pdfWriter) newPage() {
+	p.page = &bytes.Buffer{}
+	p.pages = append(p.pages, p.page)
+	p.y = pageHeight - pageMargin
+}
+
+func (p *pdfWriter) text(x, y, size float64, font, s string) {
+	fmt.Fprintf(p.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+func (p *pdfWriter) textRight(x, y, size float64, font, s string) {
+	p.text(x-textWidth(s, size), y, size, font, s)
+}
+
+func (p *pdfWriter) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(p.page, "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+func (p *pdfWriter) ensureSpace(height float64) bool {
+	if p.y-height < pageMargin {
+		p.newPage()
+		return true
+	}
+	return false
+}
+
+func (s *Statement) RenderPDF() ([]byte, error) {
+	p := &pdfWriter{}
+	p.newPage()
+
+	p.text(pageMargin, p.y, 18, fontBold, "FlexiBudget Meal Plan Statement")
+	p.y -= 24
+	p.text(pageMargin, p.y, 10, fontRegular, fmt.Sprintf("%s - Student ID %s", s.Name, s.StudentID))
+	p.y -= 14
+	p.text(pageMargin, p.y, 10, fontRegular, "Statement period: "+s.PeriodStart.Format("January 2006"))
+	p.y -= 14
+	p.text(pageMargin, p.y, 10, fontRegular, "Generated "+s.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	p.y -= 28
+
+	summary := []struct {
+		label  string
+		amount float64
+	}{
+		{"Opening balance", s.OpeningBalance},
+		{"Deposits", s.TotalDeposits},
+		{"Adjustments", s.TotalAdjustments},
+		{"Refunds", s.TotalRefunds},
+		{"Purchases", s.TotalPurchases},
+		{"Closing balance", s.ClosingBalance},
+	}
+	for i, row := range summary {
+		font := fontRegular
+		if i == len(summary)-1 {
+			font = fontBold
+		}
+		p.text(pageMargin, p.y, 11, font, row.label)
+		p.textRight(pageMargin+250, p.y, 11, font, formatMoney(row.amount))
+		p.y -= rowHeight
+	}
+	if s.SwipesUsed > 0 {
+		p.text(pageMargin, p.y, 11, fontRegular, "Meal swipes used")
+		p.textRight(pageMargin+250, p.y, 11, fontRegular, fmt.Sprintf("%d", s.SwipesUsed))
+		p.y -= rowHeight
+	}
+	p.y -= 16
+
+	p.text(pageMargin, p.y, 14, fontBold, "Activity")
+	p.y -= 20
+	p.tableHeader()
+
+	if len(s.Entries) == 0 {
+		p.text(pageMargin, p.y, 10, fontRegular, "No activity this period.")
+	}
+	for _, e := range s.Entries {
+		if p.ensureSpace(rowHeight) {
+			p.tableHeader()
+		}
+		p.text(pageMargin, p.y, 9, fontRegular, e.Date.Format("2006-01-02"))
+		p.text(pageMargin+62, p.y, 9, fontRegular, e.Kind)
+		p.text(pageMargin+112, p.y, 9, fontRegular, truncate(e.Location, maxLocation))
+		p.text(pageMargin+232, p.y, 9, fontRegular, truncate(e.Description, maxDescription))
+		p.textRight(pageWidth-pageMargin-80, p.y, 9, fontRegular, formatMoney(e.Amount))
+		p.textRight(pageWidth-pageMargin, p.y, 9, fontRegular, formatMoney(e.Balance))
+		p.y -= rowHeight
+	}
+
+	return p.bytes(), nil
+}
+
+func (p *pdfWriter) tableHeader() {
+	p.text(pageMargin, p.y, 9, fontBold, "Date")
+	p.text(pageMargin+62, p.y, 9, fontBold, "Type")
+	p.text(pageMargin+112, p.y, 9, fontBold, "Location")
+	p.text(pageMargin+232, p.y, 9, fontBold, "Description")
+	p.textRight(pageWidth-pageMargin-80, p.y, 9, fontBold, "Amount")
+	p.textRight(pageWidth-pageMargin, p.y, 9, fontBold, "Balance")
+	p.line(pageMargin, p.y-4, pageWidth-pageMargin, p.y-4)
+	p.y -= rowHeight + 2
+}
+
+func (p *pdfWriter) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	firstPage := 5
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range p.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, fontRegular, fontBold, firstPage+i*2+1))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+var helveticaWidths = map[rune]float64{
+	'.': 278, ',': 278, '-': 333, ' ': 278,
+	'A': 667, 'B': 667, 'a': 556, 'c': 500, 'e': 556, 'l': 222, 'm': 833, 'n': 556, 'o': 556, 't': 278, 'u': 556,
+}
+
+func textWidth(s string, size float64) float64 {
+	var width float64
+	for _, r := range s {
+		w, ok := helveticaWidths[r]
+		if !ok {
+			w = 556
+		}
+		width += w
+	}
+	return width * size / 1000
+}
@@ -0,0 +1,189 @@
+package statements
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+const (
+	EntryPurchase   = "purchase"
+	EntryRefund     = "refund"
+	EntryDeposit    = "deposit"
+	EntryAdjustment = "adjustment"
+)
+
+type Entry struct {
+	Date        time.Time `json:"date"`
+	Kind        string    `json:"kind"`
+	Location    string    `json:"location"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+	Balance     float64   `json:"balance"`
+}
+
+type Statement struct {
+	UserID           int64     `json:"user_id"`
+	StudentID        string    `json:"student_id"`
+	Name             string    `json:"name"`
+	PeriodStart      time.Time `json:"period_start"`
+	PeriodEnd        time.Time `json:"period_end"`
+	OpeningBalance   float64   `json:"opening_balance"`
+	TotalPurchases   float64   `json:"total_purchases"`
+	TotalRefunds     float64   `json:"total_refunds"`
+	TotalDeposits    float64   `json:"total_deposits"`
+	TotalAdjustments float64   `json:"total_adjustments"`
+	ClosingBalance   float64   `json:"closing_balance"`
+	SwipesUsed       int       `json:"swipes_used"`
+	Entries          []Entry   `json:"entries"`
+	GeneratedAt      time.Time `json:"generated_at"`
+}
+
+func ParseMonth(month string) (time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", month)
+	}
+	return start, nil
+}
+
+func Generate(db *models.DB, userID int64, month time.Time) (*Statement, error) {
+	user, err := db.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userID)
+	}
+
+	balance, err := db.GetUserBalance(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	end := start.AddDate(0, 1, 0)
+
+	spentBefore, err := db.GetTransactionTotalBefore(userID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	depositedBefore, err := db.GetSettledDepositTotal(userID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustedBefore, err := db.GetAdjustmentTotal(userID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := db.GetTransactionsBetween(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	deposits, err := db.GetSettledDepositsBetween(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustments, err := db.GetAdjustmentsBetween(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &Statement{
+		UserID:         user.ID,
+		StudentID:      user.StudentID,
+		Name:           user.Name,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		OpeningBalance: balance.StartingBalance + depositedBefore + adjustedBefore - spentBefore,
+		Entries:        make([]Entry, 0, len(transactions)+len(deposits)+len(adjustments)),
+		GeneratedAt:    time.Now(),
+	}
+
+	for _, d := range deposits {
+		stmt.TotalDeposits += d.Amount
+		stmt.Entries = append(stmt.Entries, Entry{
+			Date:        *d.SettledAt,
+			Kind:        EntryDeposit,
+			Location:    depositLabel(d.Source),
+			Description: d.Note,
+			Amount:      d.Amount,
+		})
+	}
+
+	for _, adj := range adjustments {
+		stmt.TotalAdjustments += adj.Amount
+		stmt.Entries = append(stmt.Entries, Entry{
+			Date:        adj.CreatedAt,
+			Kind:        EntryAdjustment,
+			Location:    "Account adjustment",
+			Description: adj.Reason,
+			Amount:      adj.Amount,
+		})
+	}
+
+	for _, tx := range transactions {
+		if tx.Asset == models.AssetSwipes {
+			stmt.SwipesUsed += int(tx.Amount)
+			continue
+		}
+		entry := Entry{
+			Date:        tx.TransactionDate,
+			Location:    tx.Location,
+			Description: tx.Description,
+		}
+		if tx.Amount >= 0 {
+			entry.Kind = EntryPurchase
+			entry.Amount = -tx.Amount
+			stmt.TotalPurchases += tx.Amount
+		} else {
+			entry.Kind = EntryRefund
+			entry.Amount = -tx.Amount
+			stmt.TotalRefunds += -tx.Amount
+		}
+		stmt.Entries = append(stmt.Entries, entry)
+	}
+
+	sort.SliceStable(stmt.Entries, func(i, j int) bool {
+		return stmt.Entries[i].Date.Before(stmt.Entries[j].Date)
+	})
+
+	running := stmt.OpeningBalance
+	for i := range stmt.Entries {
+		running += stmt.Entries[i].Amount
+		stmt.Entries[i].Balance = running
+	}
+	stmt.ClosingBalance = running
+
+	return stmt, nil
+}
+
+func depositLabel(source string) string {
+	switch source {
+	case models.DepositSourceTopUp:
+		return "Top-up"
+	case models.DepositSourceAdminCredit:
+		return "Account credit"
+	case models.DepositSourcePlanLoad:
+		return "Meal plan load"
+	}
+	return "Deposit"
+}
+
+func (s *Statement) Month() string {
+	return s.PeriodStart.Format("2006-01")
+}
+
+func formatMoney(amount float64) string {
+	if amount < 0 {
+		return fmt.Sprintf("-$%.2f", -amount)
+	}
+	return fmt.Sprintf("$%.2f", amount)
+}
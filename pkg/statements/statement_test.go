@@ -0,0 +1,133 @@
+package statements
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyne/flexibudget/pkg/models"
+)
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func TestGenerateTotals(t *testing.T) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "statements.db"))
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer db.Close()
+
+	user, err := db.CreateUser("63000001", "Statement User", "63000001@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	admin, err := db.CreateUser("63000002", "Statement Admin", "63000002@example.edu", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	balance, err := db.GetUserBalance(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserBalance: %v", err)
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0)
+	end := start.AddDate(0, 1, 0)
+	day := func(n int) time.Time { return start.AddDate(0, 0, n).Add(12 * time.Hour) }
+
+	_, _, err = db.ImportTransactions(user.ID, []models.Transaction{
+		{Amount: 100.00, Location: "Market Cafe", Description: "Before", TransactionDate: start.Add(-time.Hour)},
+		{Amount: 12.34, Location: "Market Cafe", Description: "Lunch", TransactionDate: day(1)},
+		{Amount: -2.50, Location: "Market Cafe", Description: "Refund", TransactionDate: day(3)},
+		{Amount: 45.67, Location: "Campus Store", Description: "Books", TransactionDate: day(5)},
+		{Amount: 7.00, Location: "Market Cafe", Description: "After", TransactionDate: end},
+	})
+	if err != nil {
+		t.Fatalf("ImportTransactions: %v", err)
+	}
+
+	deposit, err := db.CreateDeposit(user.ID, models.DepositSourceTopUp, 25.10, "test", "Card top-up", nil)
+	if err != nil {
+		t.Fatalf("CreateDeposit: %v", err)
+	}
+	if _, err := db.SettleDeposit(deposit.Reference, "test_"+deposit.Reference); err != nil {
+		t.Fatalf("SettleDeposit: %v", err)
+	}
+	adj, err := db.CreateManualAdjustment(user.ID, -3.33, "Correction", admin.ID)
+	if err != nil {
+		t.Fatalf("CreateManualAdjustment: %v", err)
+	}
+	for _, q := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE deposits SET settled_at = ? WHERE reference = ?`, []interface{}{day(2), deposit.Reference}},
+		{`UPDATE balance_adjustments SET created_at = ? WHERE id = ?`, []interface{}{day(4), adj.ID}},
+		{`INSERT INTO transactions (user_id, amount, location, description, asset, transaction_date) VALUES (?, 2, 'Market Cafe', 'Dinner', ?, ?)`,
+			[]interface{}{user.ID, models.AssetSwipes, day(6)}},
+	} {
+		if _, err := db.Exec(q.query, q.args...); err != nil {
+			t.Fatalf("%s: %v", q.query, err)
+		}
+	}
+
+	stmt, err := Generate(db, user.ID, start)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	opening := balance.StartingBalance - 100.00
+	totals := []struct {
+		name      string
+		got, want float64
+	}{
+		{"opening balance", stmt.OpeningBalance, opening},
+		{"purchases", stmt.TotalPurchases, 58.01},
+		{"refunds", stmt.TotalRefunds, 2.50},
+		{"deposits", stmt.TotalDeposits, 25.10},
+		{"adjustments", stmt.TotalAdjustments, -3.33},
+		{"closing balance", stmt.ClosingBalance, opening - 33.74},
+	}
+	for _, tt := range totals {
+		if cents(tt.got) != cents(tt.want) {
+			t.Errorf("%s = %.2f, want %.2f", tt.name, tt.got, tt.want)
+		}
+	}
+	if stmt.SwipesUsed != 2 {
+		t.Errorf("swipes used = %d, want 2", stmt.SwipesUsed)
+	}
+
+	entries := []struct {
+		kind    string
+		amount  float64
+		balance float64
+	}{
+		{EntryPurchase, -12.34, opening - 12.34},
+		{EntryDeposit, 25.10, opening + 12.76},
+		{EntryRefund, 2.50, opening + 15.26},
+		{EntryAdjustment, -3.33, opening + 11.93},
+		{EntryPurchase, -45.67, opening - 33.74},
+	}
+	if len(stmt.Entries) != len(entries) {
+		t.Fatalf("%d entries, want %d: %+v", len(stmt.Entries), len(entries), stmt.Entries)
+	}
+	for i, want := range entries {
+		got := stmt.Entries[i]
+		if got.Kind != want.kind || cents(got.Amount) != cents(want.amount) || cents(got.Balance) != cents(want.balance) {
+			t.Errorf("entry %d = %s %.2f (balance %.2f), want %s %.2f (balance %.2f)", i,
+				got.Kind, got.Amount, got.Balance, want.kind, want.amount, want.balance)
+		}
+	}
+
+	asOf, err := db.GetBalanceAt(user.ID, end)
+	if err != nil {
+		t.Fatalf("GetBalanceAt: %v", err)
+	}
+	if cents(asOf) != cents(stmt.ClosingBalance) {
+		t.Errorf("closing balance %.2f does not match ledger balance at period end %.2f", stmt.ClosingBalance, asOf)
+	}
+}
@@ -0,0 +1,142 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var rfc6238Secret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func fixedClock(at time.Time) func() time.Time {
+	return func() time.Time { return at }
+}
+
+func TestCodeAtRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	otp := &TOTP{Period: 30 * time.Second, Digits: 8}
+	for _, tt := range tests {
+		code, err := otp.CodeAt(rfc6238Secret, otp.Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("CodeAt(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+
+		otp.Now = fixedClock(time.Unix(tt.unix, 0))
+		if got, err := otp.Code(rfc6238Secret); err != nil || got != tt.code {
+			t.Errorf("Code at %d = %s, %v; want %s", tt.unix, got, err, tt.code)
+		}
+	}
+}
+
+func TestCodeAtSixDigits(t *testing.T) {
+	otp := New()
+	code, err := otp.CodeAt(rfc6238Secret, otp.Step(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "287082" {
+		t.Fatalf("CodeAt = %s, want 287082", code)
+	}
+
+	spaced := strings.ToLower(rfc6238Secret[:8] + " " + rfc6238Secret[8:])
+	if again, err := otp.CodeAt(spaced, otp.Step(time.Unix(59, 0))); err != nil || again != code {
+		t.Fatalf("CodeAt with lowercase spaced secret = %s, %v; want %s", again, err, code)
+	}
+}
+
+func TestValidateSkewWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	otp := New()
+	otp.Now = fixedClock(now)
+	current := otp.Step(now)
+
+	tests := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, tt := range tests {
+		code, err := otp.CodeAt(rfc6238Secret, current+tt.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := otp.Validate(rfc6238Secret, code, 0)
+		if ok != tt.ok {
+			t.Errorf("code for step offset %d: ok = %v, want %v", tt.offset, ok, tt.ok)
+		}
+		if ok && step != current+tt.offset {
+			t.Errorf("code for step offset %d: step = %d, want %d", tt.offset, step, current+tt.offset)
+		}
+	}
+
+	otp.Skew = 0
+	previous, _ := otp.CodeAt(rfc6238Secret, current-1)
+	if _, ok := otp.Validate(rfc6238Secret, previous, 0); ok {
+		t.Error("previous step accepted with Skew = 0")
+	}
+}
+
+func TestValidateRejectsReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	otp := New()
+	otp.Now = fixedClock(now)
+
+	code, err := otp.Code(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok := otp.Validate(rfc6238Secret, code, 0)
+	if !ok {
+		t.Fatal("current code rejected")
+	}
+	if _, ok := otp.Validate(rfc6238Secret, code, step); ok {
+		t.Fatal("code accepted again after its step was used")
+	}
+
+	otp.Now = fixedClock(now.Add(otp.Period))
+	previous, _ := otp.CodeAt(rfc6238Secret, step)
+	if _, ok := otp.Validate(rfc6238Secret, previous, step); ok {
+		t.Fatal("used code accepted from inside the skew window")
+	}
+	next, _ := otp.Code(rfc6238Secret)
+	if got, ok := otp.Validate(rfc6238Secret, next, step); !ok || got != step+1 {
+		t.Fatalf("next code: step = %d, ok = %v; want %d, true", got, ok, step+1)
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	otp := New()
+	otp.Now = fixedClock(time.Unix(59, 0))
+
+	code, _ := otp.Code(rfc6238Secret)
+	if _, ok := otp.Validate(rfc6238Secret, code[:3]+" "+code[3:], 0); !ok {
+		t.Error("code with a space rejected")
+	}
+	for _, bad := range []string{"", code[:5], code + "0", "abcdef"} {
+		if _, ok := otp.Validate(rfc6238Secret, bad, 0); ok {
+			t.Errorf("Validate(%q) accepted", bad)
+		}
+	}
+	if _, ok := otp.Validate("not base32!", code, 0); ok {
+		t.Error("invalid secret accepted")
+	}
+}
@@ -1,112 +1,4 @@
 document.addEventListener('DOMContentLoaded', function() {
-    const menuItems = [
-        {
-            id: 1,
-            name: "Avocado Toast",
-            price: "$8.99",
-            mealType: "breakfast",
-            dietaryRestrictions: ["vegetarian", "dairy-free"]
-        },
-        {
-            id: 2,
-            name: "Belgian Waffle",
-            price: "$7.50",
-            mealType: "breakfast",
-            dietaryRestrictions: ["vegetarian"]
-        },
-        {
-            id: 3,
-            name: "Breakfast Burrito",
-            price: "$9.25",
-            mealType: "breakfast",
-            dietaryRestrictions: []
-        },
-        {
-            id: 4,
-            name: "Greek Yogurt Parfait",
-            price: "$5.99",
-            mealType: "breakfast",
-            dietaryRestrictions: ["vegetarian", "gluten-free"]
-        },
-        {
-            id: 5,
-            name: "Grilled Chicken Sandwich",
-            price: "$10.50",
-            mealType: "lunch",
-            dietaryRestrictions: []
-        },
-        {
-            id: 6,
-            name: "Quinoa Bowl",
-            price: "$11.25",
-            mealType: "lunch",
-            dietaryRestrictions: ["vegetarian", "gluten-free", "dairy-free"]
-        },
-        {
-            id: 7,
-            name: "Caprese Panini",
-            price: "$9.99",
-            mealType: "lunch",
-            dietaryRestrictions: ["vegetarian"]
-        },
-        {
-            id: 8,
-            name: "Caesar Salad",
-            price: "$8.75",
-            mealType: "lunch",
-            dietaryRestrictions: []
-        },
-        {
-            id: 9,
-            name: "Mushroom Risotto",
-            price: "$13.50",
-            mealType: "dinner",
-            dietaryRestrictions: ["vegetarian", "gluten-free"]
-        },
-        {
-            id: 10,
-            name: "Grilled Salmon",
-            price: "$16.99",
-            mealType: "dinner",
-            dietaryRestrictions: ["gluten-free", "dairy-free"]
-        },
-        {
-            id: 11,
-            name: "Margherita Pizza",
-            price: "$12.50",
-            mealType: "dinner",
-            dietaryRestrictions: ["vegetarian"]
-        },
-        {
-            id: 12,
-            name: "Beef Stir-Fry",
-            price: "$14.25",
-            mealType: "dinner",
-            dietaryRestrictions: ["dairy-free"]
-        },
-        {
-            id: 13, 
-            name: "Vegan Burger",
-            price: "$11.99",
-            mealType: "lunch",
-            dietaryRestrictions: ["vegan", "vegetarian", "dairy-free"]
-        },
-        {
-            id: 14,
-            name: "Fruit Smoothie Bowl",
-            price: "$7.99",
-            mealType: "breakfast",
-            dietaryRestrictions: ["vegan", "vegetarian", "gluten-free", "dairy-free"]
-        },
-        {
-            id: 15,
-            name: "Pasta Primavera",
-            price: "$13.99",
-            mealType: "dinner",
-            dietaryRestrictions: ["vegetarian"]
-        }
-    ];
-
     const menuContainer = document.getElementById('menu-container');
     const mealTypeFilter = document.getElementById('meal-type');
     const dietaryFilter = document.getElementById('dietary-restriction');
@@ -126,13 +18,13 @@ document.addEventListener('DOMContentLoaded', function() {
             
             const mealTypeBadge = document.createElement('div');
             mealTypeBadge.className = 'menu-item-type';
-            mealTypeBadge.textContent = item.mealType.charAt(0).toUpperCase() + item.mealType.slice(1);
+            mealTypeBadge.textContent = item.meal_type.charAt(0).toUpperCase() + item.meal_type.slice(1);
             
             const dietaryTags = document.createElement('div');
             dietaryTags.className = 'dietary-tags';
             
-            if (item.dietaryRestrictions.length > 0) {
-                item.dietaryRestrictions.forEach(restriction => {
+            if (item.dietary_restrictions.length > 0) {
+                item.dietary_restrictions.forEach(restriction => {
                     const tagElement = document.createElement('span');
                     tagElement.className = 'dietary-tag';
                     
@@ -162,12 +54,22 @@ document.addEventListener('DOMContentLoaded', function() {
                 });
             }
             
-            menuItemElement.innerHTML = `
-                <div class="menu-item-content">
-                    <h3>${item.name}</h3>
-                    <div class="menu-item-price">${item.price}</div>
-                </div>
-            `;
+            const contentElement = document.createElement('div');
+            contentElement.className = 'menu-item-content';
+            
+            const nameElement = document.createElement('h3');
+            nameElement.textContent = item.name;
+            
+            const locationElement = document.createElement('div');
+            locationElement.className = 'menu-item-location';
+            locationElement.textContent = item.location;
+            
+            const priceElement = document.createElement('div');
+            priceElement.className = 'menu-item-price';
+            priceElement.textContent = '$' + item.price.toFixed(2);
+            
+            contentElement.append(nameElement, locationElement, priceElement);
+            menuItemElement.appendChild(contentElement);
             
             menuItemElement.prepend(mealTypeBadge);
             menuItemElement.appendChild(dietaryTags);
@@ -176,37 +78,35 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
-    function filterMenuItems() {
-        const mealType = mealTypeFilter.value;
-        const dietaryRestriction = dietaryFilter.value;
-        const searchTerm = searchInput.value.toLowerCase();
+    async function filterMenuItems() {
+        const params = new URLSearchParams();
         
-        let filteredItems = menuItems;
-        
-        if (mealType !== 'all') {
-            filteredItems = filteredItems.filter(item => item.mealType === mealType);
+        if (mealTypeFilter.value !== 'all') {
+            params.set('meal_type', mealTypeFilter.value);
         }
         
-        if (dietaryRestriction !== 'all') {
-            filteredItems = filteredItems.filter(item => 
-                item.dietaryRestrictions.includes(dietaryRestriction)
-            );
+        if (dietaryFilter.value !== 'all') {
+            params.set('dietary', dietaryFilter.value);
         }
         
+        const searchTerm = searchInput.value.trim();
         if (searchTerm) {
-            filteredItems = filteredItems.filter(item => 
-                item.name.toLowerCase().includes(searchTerm)
-            );
+            params.set('q', searchTerm);
         }
         
-        renderMenuItems(filteredItems);
+        try {
+            const items = await fetchAPI('/api/dining/menu?' + params.toString());
+            renderMenuItems(items);
+        } catch (error) {
+            menuContainer.innerHTML = '<div class="no-results">Failed to load the menu</div>';
+        }
     }
 
     mealTypeFilter.addEventListener('change', filterMenuItems);
     dietaryFilter.addEventListener('change', filterMenuItems);
     searchInput.addEventListener('input', filterMenuItems);
 
-    renderMenuItems(menuItems);
+    filterMenuItems();
 
     const userNameElement = document.getElementById('userName');
     if (userNameElement) {
@@ -1,4 +1,29 @@
-async function fetchAPI(endpoint, options = {}) {
+let refreshInFlight = null;
+
+async function refreshSession() {
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) return false;
+
+  if (!refreshInFlight) {
+    refreshInFlight = fetch('/api/auth/refresh', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken })
+    }).then(async response => {
+      if (!response.ok) return false;
+      const data = await response.json();
+      localStorage.setItem('authToken', data.token);
+      localStorage.setItem('refreshToken', data.refresh_token);
+      return true;
+    }).catch(() => false).finally(() => {
+      refreshInFlight = null;
+    });
+  }
+
+  return refreshInFlight;
+}
+
+async function fetchAPI(endpoint, options = {}, retried = false) {
   const token = localStorage.getItem('authToken');
   console.log(`API call to ${endpoint}`, { token: !!token });
   
@@ -16,8 +41,13 @@ async function fetchAPI(endpoint, options = {}) {
     
     console.log(`API response from ${endpoint}:`, { status: response.status });
     
+    if (response.status === 401 && !retried && await refreshSession()) {
+      return fetchAPI(endpoint, options, true);
+    }
+    
     if (response.status === 401) {
       localStorage.removeItem('authToken');
+      localStorage.removeItem('refreshToken');
       localStorage.removeItem('userId');
       localStorage.removeItem('userName');
       localStorage.removeItem('studentId');
@@ -69,7 +99,9 @@ async function loadUserData() {
       
       currentWeekSpent: balance.spent_amount,
         
-      budgetPercentage: Math.round((balance.spent_amount / budget.weekly_budget) * 100)
+      budgetPercentage: Math.round((balance.spent_amount / budget.weekly_budget) * 100),
+
+      swipes: balance.assets ? balance.assets.swipes : null
     };
     
     updateUI();
@@ -100,6 +132,13 @@ function updateUI() {
   if (currentWeekSpentEl) currentWeekSpentEl.textContent = userData.currentWeekSpent.toFixed(2);
   
   if (budgetPercentageEl) budgetPercentageEl.textContent = userData.budgetPercentage + '%';
+
+  const swipesCardEl = document.getElementById('swipes-card');
+  if (swipesCardEl && userData.swipes && userData.swipes.allowance > 0) {
+    swipesCardEl.style.display = '';
+    document.getElementById('swipes-remaining').textContent = userData.swipes.remaining + ' / ' + userData.swipes.allowance;
+    document.getElementById('swipes-reset').textContent = userData.swipes.reset === 'weekly' ? 'remaining this week' : 'remaining this term';
+  }
   
   if (budgetProgressEl) budgetProgressEl.style.width = Math.min(userData.budgetPercentage, 100) + '%';
   
@@ -238,6 +277,51 @@ function showWarning(message) {
   }, 5000);
 }
 
+function showDemoBanner(message) {
+  if (document.getElementById('demo-banner')) return;
+
+  const banner = document.createElement('div');
+  banner.id = 'demo-banner';
+  banner.innerHTML = '<i class="fas fa-flask"></i> <span></span>';
+  banner.querySelector('span').textContent = message;
+
+  banner.style.backgroundColor = 'var(--warning-color)';
+  banner.style.color = 'white';
+  banner.style.padding = '8px 20px';
+  banner.style.textAlign = 'center';
+  banner.style.fontWeight = 'bold';
+
+  document.body.prepend(banner);
+}
+
+async function checkAccountStatus() {
+  try {
+    const user = await fetchAPI('/api/users/me');
+    if (user.demo) {
+      showDemoBanner(user.demo_banner || 'Demo mode');
+    }
+    if (user.email_verified) {
+      return;
+    }
+
+    showWarning(`Please verify ${user.email} to enable deposits, dining orders and fairy requests. <a href="#" id="resend-verification" style="color: white; text-decoration: underline;">Resend link</a>`);
+    document.getElementById('resend-verification').addEventListener('click', async (e) => {
+      e.preventDefault();
+      const response = await fetch('/api/auth/email/resend', {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${localStorage.getItem('authToken')}` }
+      });
+      if (response.ok) {
+        showMessage('Verification email sent');
+      } else {
+        showMessage((await response.text()).trim() || 'Failed to send verification email', 'error');
+      }
+    });
+  } catch (error) {
+    console.error('Failed to check account status:', error);
+  }
+}
+
 function addLogoutButton() {
   const menu = document.querySelector('.menu');
   if (!menu) return;
@@ -256,11 +340,15 @@ function addLogoutButton() {
 
 async function logoutUser() {
   try {
-    await fetchAPI('/api/logout', { method: 'POST' });
+    await fetchAPI('/api/logout', {
+      method: 'POST',
+      body: JSON.stringify({ refresh_token: localStorage.getItem('refreshToken') })
+    });
   } catch (error) {
     console.error('Logout error:', error);
   } finally {
     localStorage.removeItem('authToken');
+    localStorage.removeItem('refreshToken');
     localStorage.removeItem('userId');
     localStorage.removeItem('userName');
     localStorage.removeItem('studentId');
@@ -294,6 +382,7 @@ document.addEventListener('DOMContentLoaded', async () => {
     
     try {
       await loadUserData();
+      checkAccountStatus();
     } catch (error) {
       console.error('Failed to load initial data:', error);
       
@@ -34,6 +34,7 @@ document.addEventListener('DOMContentLoaded', () => {
           logoutUser();
         } else {
           localStorage.removeItem('authToken');
+          localStorage.removeItem('refreshToken');
           localStorage.removeItem('userId');
           localStorage.removeItem('userName');
           localStorage.removeItem('studentId');